@@ -0,0 +1,375 @@
+package cli
+
+// This package runs a single command given on the command line instead of the interactive prompt, so that the dictionary can be used from scripts
+// Every command returns one of the exit codes below, which main passes on to the shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"japp/cmdoutput"
+	"japp/env"
+	"japp/examples"
+	"japp/kanjidic"
+	"japp/radicals"
+	"japp/server"
+	"japp/wordsearch"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExitFound     = 0 // The command succeeded and found what it was asked for
+	ExitNoResults = 1 // The command succeeded but the search came back empty
+	ExitError     = 2 // Bad usage, or the environment could not be loaded
+)
+
+const usage = `Usage:
+  japp                              start the interactive prompt
+  japp search <query> [flags]       search the dictionary once and print the results
+  japp info <wordID> [--format f]   print a single entry by its WordID
+  japp kanji <kanji> [--format f]   print the readings, meanings and statistics of a single kanji
+  japp examples <word> [--limit n] [--format f]
+                                    print example sentences using the word
+  japp radicals <radical>... [--pick n] [--format f]
+                                    list the kanji made of the given radicals (characters or names like water, tree),
+                                    or search for the n-th of them
+  japp rebuild-index                rebuild the envfile even if it is up to date
+  japp serve [--addr a] [--timeout t]
+                                    answer lookups over HTTP: /search?q=, /entry/{wordID}, /kanji/{char}
+  japp help                         show this message
+
+Flags of the search command:
+`
+
+// Run executes the command in args (the program arguments without the program name) and returns the exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		return usageError("no command given")
+	}
+	switch args[0] {
+	case "search":
+		return search(args[1:])
+	case "info":
+		return info(args[1:])
+	case "kanji":
+		return kanji(args[1:])
+	case "examples":
+		return exampleSentences(args[1:])
+	case "radicals":
+		return radicalSearch(args[1:])
+	case "rebuild-index":
+		return rebuildIndex(args[1:])
+	case "serve":
+		return serve(args[1:])
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return ExitFound
+	}
+	return usageError(fmt.Sprintf("unknown command %q", args[0]))
+}
+
+// searchSettings holds the values of the search command's flags
+type searchSettings struct {
+	limit    *int
+	offset   *int
+	format   *string
+	script   *string
+	match    *string
+	dict     *string
+	names    *string
+	examples *int
+	fold     *bool
+}
+
+func searchFlags() (*flag.FlagSet, searchSettings) {
+	var settings searchSettings
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	settings.limit = flags.Int("limit", wordsearch.DefaultLimit, "maximum number of results to print, 0 prints all of them")
+	settings.offset = flags.Int("offset", 0, "number of results to skip, to get the following pages")
+	settings.format = flags.String("format", "text", "output format: "+strings.Join(cmdoutput.FormatNames(), ", "))
+	settings.script = flags.String("script", "auto", "how to read Latin letters: auto, en or romaji")
+	settings.match = flags.String("match", "prefix", "part of the word the query has to match: prefix, exact, suffix or contains")
+	settings.dict = flags.String("dict", "auto", "dictionary to search: words, names, or auto for names only when no word matches")
+	settings.names = flags.String("name-type", "", "only keep names of this type: surname, given, place, company, person or product")
+	settings.examples = flags.Int("examples", wordsearch.DefaultExamples, "number of example sentences shown under every result")
+	settings.fold = flags.Bool("fold-kana", false, "let kana queries match readings written in the other script, e.g. ネコ finds ねこ")
+	return flags, settings
+}
+
+func search(args []string) int {
+	flags, settings := searchFlags()
+	words, err := parseInterspersed(flags, args)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	query := strings.Join(words, " ")
+	if query == "" {
+		return usageError("search needs a query")
+	}
+	var options wordsearch.Options
+	if options.Script, err = wordsearch.ParseScript(*settings.script); err != nil {
+		return usageError(err.Error())
+	}
+	if options.Match, err = wordsearch.ParseMatchMode(*settings.match); err != nil {
+		return usageError(err.Error())
+	}
+	if options.Dictionary, err = wordsearch.ParseDictionary(*settings.dict); err != nil {
+		return usageError(err.Error())
+	}
+	if options.NameType, err = wordsearch.ParseNameType(*settings.names); err != nil {
+		return usageError(err.Error())
+	}
+	formatter, err := cmdoutput.NewFormatter(*settings.format)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	if *settings.limit < 0 || *settings.offset < 0 {
+		return usageError("limit and offset cannot be negative")
+	}
+	options.Limit, options.Offset = *settings.limit, *settings.offset
+	options.FoldKana = *settings.fold
+	options.Examples = *settings.examples
+	if options.Examples <= 0 {
+		options.Examples = wordsearch.NoExamples
+	}
+	table, err := env.Initialize()
+	if err != nil {
+		return failure(err)
+	}
+	page := wordsearch.SearchPage(*table, query, options)
+	if err = formatter.Format(os.Stdout, *table, page, query); err != nil {
+		return failure(err)
+	}
+	if page.Total == 0 {
+		return ExitNoResults
+	}
+	return ExitFound
+}
+
+func info(args []string) int {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "text", "output format")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	if len(args) != 1 {
+		return usageError("info needs exactly one WordID")
+	}
+	wordID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return usageError(fmt.Sprintf("invalid WordID %q", args[0]))
+	}
+	formatter, err := cmdoutput.NewFormatter(*format)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	table, err := env.Initialize()
+	if err != nil {
+		return failure(err)
+	}
+	if err = cmdoutput.FormatEntry(os.Stdout, formatter, *table, wordID); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitNoResults
+	}
+	return ExitFound
+}
+
+func kanji(args []string) int {
+	flags := flag.NewFlagSet("kanji", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "text", "output format")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	if len(args) != 1 || !kanjidic.IsSingleKanji(args[0]) {
+		return usageError("kanji needs exactly one kanji")
+	}
+	formatter, err := cmdoutput.NewFormatter(*format)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	table, err := env.Initialize()
+	if err != nil {
+		return failure(err)
+	}
+	if table.Kanjidic == nil {
+		return failure(errors.New(kanjidic.MissingMessage))
+	}
+	info, found := kanjidic.Lookup(table.Kanjidic, table.KanjiIndex, args[0])
+	if !found {
+		fmt.Fprintf(os.Stderr, "%v is not in KANJIDIC2\n", args[0])
+		return ExitNoResults
+	}
+	if err = cmdoutput.FormatKanji(os.Stdout, formatter, info); err != nil {
+		return failure(err)
+	}
+	return ExitFound
+}
+
+func radicalSearch(args []string) int {
+	flags := flag.NewFlagSet("radicals", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	pick := flags.Int("pick", 0, "search for the kanji with this number in the list")
+	format := flags.String("format", "text", "output format of the search started with --pick")
+	components, err := parseInterspersed(flags, args)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	if len(components) == 0 {
+		return usageError("radicals needs at least one radical")
+	}
+	formatter, err := cmdoutput.NewFormatter(*format)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	table, err := env.Initialize()
+	if err != nil {
+		return failure(err)
+	}
+	if table.Radicals == nil {
+		return failure(errors.New(radicals.MissingMessage))
+	}
+	strokes := kanjidic.StrokeCounts(table.Kanjidic)
+	found, err := table.Radicals.Find(components, func(kanji string) int { return strokes[kanji] })
+	if err != nil {
+		return usageError(err.Error())
+	}
+	if *pick == 0 {
+		cmdoutput.PrintRadicalSearch(os.Stdout, found, strokes, table.Radicals.Remaining(found, components))
+		if len(found) == 0 {
+			return ExitNoResults
+		}
+		return ExitFound
+	}
+	if *pick < 0 || *pick > len(found) {
+		return usageError(fmt.Sprintf("pick must be between 1 and %v", len(found)))
+	}
+	query := found[*pick-1]
+	page := wordsearch.SearchPage(*table, query, wordsearch.Options{Limit: wordsearch.DefaultLimit})
+	if err = formatter.Format(os.Stdout, *table, page, query); err != nil {
+		return failure(err)
+	}
+	if page.Total == 0 {
+		return ExitNoResults
+	}
+	return ExitFound
+}
+
+func exampleSentences(args []string) int {
+	flags := flag.NewFlagSet("examples", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	limit := flags.Int("limit", wordsearch.DefaultLimit, "maximum number of sentences to print, 0 prints all of them")
+	format := flags.String("format", "text", "output format")
+	words, err := parseInterspersed(flags, args)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	query := strings.Join(words, " ")
+	if query == "" {
+		return usageError("examples needs a word")
+	}
+	if *limit < 0 {
+		return usageError("limit cannot be negative")
+	}
+	formatter, err := cmdoutput.NewFormatter(*format)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	table, err := env.Initialize()
+	if err != nil {
+		return failure(err)
+	}
+	if table.Examples == nil {
+		return failure(errors.New(examples.MissingMessage))
+	}
+	result, sentences, found := wordsearch.Examples(*table, query, *limit)
+	if !found {
+		fmt.Fprintf(os.Stderr, "No word matches %q\n", query)
+		return ExitNoResults
+	}
+	if err = cmdoutput.FormatExamples(os.Stdout, formatter, cmdoutput.Headword(table.Dict.Entries[result.Entry.WordID]), sentences); err != nil {
+		return failure(err)
+	}
+	if len(sentences) == 0 {
+		return ExitNoResults
+	}
+	return ExitFound
+}
+
+func rebuildIndex(args []string) int {
+	if len(args) != 0 {
+		return usageError("rebuild-index takes no arguments")
+	}
+	if _, err := env.Rebuild(); err != nil {
+		return failure(err)
+	}
+	fmt.Println("Environment rebuilt")
+	return ExitFound
+}
+
+func serve(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	address := flags.String("addr", "127.0.0.1:8080", "address to listen on")
+	timeout := flags.Duration("timeout", 10*time.Second, "time limit for a single request")
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	if flags.NArg() != 0 {
+		return usageError("serve takes no arguments besides its flags")
+	}
+	if *timeout <= 0 {
+		return usageError("timeout must be positive")
+	}
+	table, err := env.Initialize()
+	if err != nil {
+		return failure(err)
+	}
+	if err = server.New(*table, *timeout).ListenAndServe(*address); err != nil {
+		return failure(err)
+	}
+	return ExitFound
+}
+
+// The standard flag package stops at the first argument that is not a flag, while we want "search 猫 --limit 20" to work as well
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func printUsage(output io.Writer) {
+	fmt.Fprint(output, usage)
+	flags, _ := searchFlags()
+	flags.SetOutput(output)
+	flags.PrintDefaults()
+	printGlobalFlags(output)
+}
+
+func usageError(message string) int {
+	fmt.Fprintf(os.Stderr, "japp: %v\n\n", message)
+	printUsage(os.Stderr)
+	return ExitError
+}
+
+func failure(err error) int {
+	fmt.Fprintf(os.Stderr, "japp: %v\n", err)
+	return ExitError
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"japp/cmdoutput"
+	"japp/config"
+	"japp/env"
+	"os"
+)
+
+// globalFlags are given before the command, e.g. 'japp --data-dir /usr/share/japp search cat', and override the config file and the JAPP_* variables
+func globalFlags() (*flag.FlagSet, *string, *config.Config) {
+	var overrides config.Config
+	flags := flag.NewFlagSet("japp", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	path := flags.String("config", "", "config file to read instead of $XDG_CONFIG_HOME/japp/config (also $JAPP_CONFIG)")
+	for _, setting := range config.Settings {
+		flags.StringVar(setting.Field(&overrides), setting.Flag(), "", fmt.Sprintf("%v (also %v, or %v in the config file)", setting.Usage, setting.Variable(), setting.Key))
+	}
+	return flags, path, &overrides
+}
+
+// Configure reads the global flags, loads the configuration and hands it to the environment
+// It returns the arguments left after the flags, i.e. the command and its own arguments
+func Configure(args []string) ([]string, error) {
+	flags, path, overrides := globalFlags()
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return []string{"help"}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	settings, err := config.Load(*path, *overrides)
+	if err != nil {
+		return nil, err
+	}
+	env.Configure(settings)
+	if isTerminal(os.Stderr) {
+		env.BuildProgress = cmdoutput.ProgressPrinter(os.Stderr)
+	}
+	return flags.Args(), nil
+}
+
+// isTerminal tells whether the file is a terminal rather than a pipe or a regular file, so that progress lines don't end up in logs
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func printGlobalFlags(output io.Writer) {
+	fmt.Fprintf(output, "\nGlobal flags, given before the command:\n")
+	flags, _, _ := globalFlags()
+	flags.SetOutput(output)
+	flags.PrintDefaults()
+	fmt.Fprintf(output, "\nThe dictionary files are looked for in $XDG_DATA_HOME/japp and the parsed dictionaries are cached in $XDG_CACHE_HOME/japp\n")
+}
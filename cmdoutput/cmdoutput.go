@@ -2,50 +2,200 @@ package cmdoutput
 
 import (
 	"fmt"
+	"io"
 	"japp/env"
+	"japp/searchgrids"
 	"japp/wordsearch"
+	"os"
+	"sort"
+	"strings"
+
+	"foosoft.net/projects/jmdict"
 )
 
+// Formatter renders search results in one output format. New formats only need to implement it and be added with RegisterFormatter
+type Formatter interface {
+	Format(output io.Writer, table env.Environment, page wordsearch.Page, query string) error
+}
+
+var formatters = map[string]Formatter{
+	"text":     TextFormatter{},
+	"debug":    TextFormatter{Debug: true},
+	"json":     JSONFormatter{},
+	"tsv":      TSVFormatter{},
+	"markdown": MarkdownFormatter{},
+}
+
+// RegisterFormatter makes a formatter available under the given name, replacing any formatter that already had it
+func RegisterFormatter(name string, formatter Formatter) {
+	formatters[name] = formatter
+}
+
+// NewFormatter returns the formatter registered under the given name
+func NewFormatter(name string) (Formatter, error) {
+	formatter, found := formatters[name]
+	if !found {
+		return nil, fmt.Errorf("unknown format %q, available formats: %v", name, strings.Join(FormatNames(), ", "))
+	}
+	return formatter, nil
+}
+
+// FormatNames lists the names of all registered formatters in alphabetical order
+func FormatNames() []string {
+	var names []string
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrintResults prints the first page of the results, followed by how many more there are
 func PrintResults(table env.Environment, results wordsearch.ResultEntries, query string) {
-	if len(results) == 0 {
-		fmt.Println("No results")
-		return
-	}
-	for i, result := range results {
-		entry := table.Dict.Entries[result.Entry.WordID]
-		for i, kanji := range entry.Kanji {
-			if i == 0 {
-				fmt.Printf("Kanji: ")
-				fmt.Printf("%v", kanji.Expression)
-			} else {
-				fmt.Printf(", %v", kanji.Expression)
-			}
+	PrintPage(table, wordsearch.Paginate(results, 0, wordsearch.DefaultLimit))
+}
+
+// PrintPage prints a page of results in the text format
+func PrintPage(table env.Environment, page wordsearch.Page) {
+	TextFormatter{}.Format(os.Stdout, table, page, "")
+}
+
+// PrintEntry prints a single dictionary entry looked up by its WordID, i.e. its index in JMdict
+func PrintEntry(table env.Environment, wordID int) error {
+	return FormatEntry(os.Stdout, TextFormatter{}, table, wordID)
+}
+
+// FormatEntry renders a single dictionary entry with the given formatter, as if it was the only result of a search
+func FormatEntry(output io.Writer, formatter Formatter, table env.Environment, wordID int) error {
+	if wordID < 0 || wordID >= len(table.Dict.Entries) {
+		return fmt.Errorf("no entry with WordID %v", wordID)
+	}
+	var result wordsearch.ResultEntry
+	result.Entry = searchgrids.Entry{WordID: wordID}
+	return formatter.Format(output, table, wordsearch.Paginate(wordsearch.ResultEntries{result}, 0, 0), "")
+}
+
+// TextFormatter is the human-readable layout used by the interactive prompt
+// In debug mode every result is followed by the components of its score, so that rankings can be audited
+type TextFormatter struct {
+	Debug bool
+}
+
+func (formatter TextFormatter) Format(output io.Writer, table env.Environment, page wordsearch.Page, query string) error {
+	if page.Total == 0 {
+		_, err := fmt.Fprintln(output, "No results")
+		return err
+	}
+	for _, result := range page.Results {
+		printEntry(output, resultEntry(table, result))
+		if result.Name {
+			fmt.Fprintf(output, "Name: %v\n", strings.Join(nameTypes(resultEntry(table, result)), ", "))
 		}
-		fmt.Printf("\n")
-		for i, reading := range entry.Readings {
-			if i == 0 {
-				fmt.Printf("Readings: ")
-				fmt.Printf("%v", reading.Reading)
-			} else {
-				fmt.Printf(", %v", reading.Reading)
-			}
+		if accents := resultPitch(table, result); len(accents) != 0 {
+			fmt.Fprintf(output, "Pitch: %v\n", strings.Join(pitchStrings(accents), ", "))
 		}
-		fmt.Printf("\n")
-		for i, sense := range entry.Sense {
-			if i == 0 {
-				fmt.Printf("Translations: ")
-			}
-			for j, gloss := range sense.Glossary {
-				if i == 0 && j == 0 {
-					fmt.Printf("%v", gloss.Content)
-				} else {
-					fmt.Printf(", %v", gloss.Content)
-				}
-			}
+		if result.Tier != wordsearch.TierNone {
+			fmt.Fprintf(output, "Match: %v%v\n", result.Tier, commonLabel(result.Common))
 		}
-		fmt.Printf("\n\n")
-		if i == 10 {
-			break
+		printExamples(output, resultExamples(table, result, page.ExampleCount()))
+		if formatter.Debug {
+			printScore(output, table, result)
+		}
+		if len(result.Inflection) != 0 {
+			fmt.Fprintf(output, "Inflection: %v\n", strings.Join(result.Inflection, " → "))
+		}
+		if _, err := fmt.Fprintf(output, "\n"); err != nil {
+			return err
+		}
+	}
+	if page.HasNext() || page.HasPrevious() {
+		_, err := fmt.Fprintln(output, pageSummary(page))
+		return err
+	}
+	return nil
+}
+
+// printScore writes the score of a result as the sum of its components, the entry score being broken down as well
+func printScore(output io.Writer, table env.Environment, result wordsearch.ResultEntry) {
+	components := result.Components
+	entry := searchgrids.ScoreEntry(resultEntry(table, result))
+	fmt.Fprintf(output, "Score: %v = entry %v + form %v + length %v\n", result.Score, components.Entry, components.Form, components.Length)
+	fmt.Fprintf(output, "Entry: %v = base %v + kanji %v + content %v + readings %v\n", entry.Total(), entry.Base, entry.Kanji, entry.Content, entry.Readings)
+	fmt.Fprintf(output, "WordID: %v, hashes: %v\n", result.Entry.WordID, result.Entry.Hash)
+}
+
+// Headword writes the main form of an entry with its reading, e.g. "猫 (ねこ)"
+func Headword(entry jmdict.JmdictEntry) string {
+	if len(entry.Readings) == 0 {
+		return ""
+	} else if len(entry.Kanji) == 0 {
+		return entry.Readings[0].Reading
+	}
+	return fmt.Sprintf("%v (%v)", entry.Kanji[0].Expression, entry.Readings[0].Reading)
+}
+
+// resultEntry finds the JMdict or JMnedict entry a result points to
+func resultEntry(table env.Environment, result wordsearch.ResultEntry) jmdict.JmdictEntry {
+	if result.Name {
+		return table.Names.Entries[result.Entry.WordID]
+	}
+	return table.Dict.Entries[result.Entry.WordID]
+}
+
+// The name types of a JMnedict entry are kept in the Misc of its senses
+func nameTypes(entry jmdict.JmdictEntry) []string {
+	var types []string
+	for _, sense := range entry.Sense {
+		types = appendUnique(types, sense.Misc...)
+	}
+	return types
+}
+
+func commonLabel(common bool) string {
+	if common {
+		return ", common word"
+	}
+	return ""
+}
+
+// pageSummary describes which part of the results a page holds, e.g. "Results 11-20 of 134"
+func pageSummary(page wordsearch.Page) string {
+	if len(page.Results) == 0 {
+		return fmt.Sprintf("No more results, the search found %v in total", page.Total)
+	}
+	return fmt.Sprintf("Results %v-%v of %v", page.Offset+1, page.Offset+len(page.Results), page.Total)
+}
+
+func printEntry(output io.Writer, entry jmdict.JmdictEntry) {
+	for i, kanji := range entry.Kanji {
+		if i == 0 {
+			fmt.Fprintf(output, "Kanji: ")
+			fmt.Fprintf(output, "%v", kanji.Expression)
+		} else {
+			fmt.Fprintf(output, ", %v", kanji.Expression)
+		}
+	}
+	fmt.Fprintf(output, "\n")
+	for i, reading := range entry.Readings {
+		if i == 0 {
+			fmt.Fprintf(output, "Readings: ")
+			fmt.Fprintf(output, "%v", reading.Reading)
+		} else {
+			fmt.Fprintf(output, ", %v", reading.Reading)
+		}
+	}
+	fmt.Fprintf(output, "\n")
+	for i, sense := range entry.Sense {
+		if i == 0 {
+			fmt.Fprintf(output, "Translations: ")
+		}
+		for j, gloss := range sense.Glossary {
+			if i == 0 && j == 0 {
+				fmt.Fprintf(output, "%v", gloss.Content)
+			} else {
+				fmt.Fprintf(output, ", %v", gloss.Content)
+			}
 		}
 	}
+	fmt.Fprintf(output, "\n")
 }
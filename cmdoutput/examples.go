@@ -0,0 +1,109 @@
+package cmdoutput
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"japp/env"
+	"japp/examples"
+	"japp/wordsearch"
+	"strings"
+)
+
+// resultExamples finds up to count example sentences of a result, names having none
+func resultExamples(table env.Environment, result wordsearch.ResultEntry, count int) []examples.Sentence {
+	if result.Name || count <= 0 {
+		return nil
+	}
+	return table.Examples.For(result.Entry.WordID, count)
+}
+
+func printExamples(output io.Writer, sentences []examples.Sentence) {
+	if len(sentences) == 0 {
+		return
+	}
+	fmt.Fprintf(output, "Examples:\n")
+	for _, sentence := range sentences {
+		fmt.Fprintf(output, "  %v\n  %v\n", sentence.Japanese, sentence.English)
+	}
+}
+
+// ExamplesFormatter is implemented by the formatters that can render the example sentences of a single word
+type ExamplesFormatter interface {
+	FormatExamples(output io.Writer, word string, sentences []examples.Sentence) error
+}
+
+// FormatExamples renders the example sentences of a word with the given formatter, falling back to the text layout if the formatter doesn't support them
+func FormatExamples(output io.Writer, formatter Formatter, word string, sentences []examples.Sentence) error {
+	if examplesFormatter, ok := formatter.(ExamplesFormatter); ok {
+		return examplesFormatter.FormatExamples(output, word, sentences)
+	}
+	return TextFormatter{}.FormatExamples(output, word, sentences)
+}
+
+func (TextFormatter) FormatExamples(output io.Writer, word string, sentences []examples.Sentence) error {
+	if len(sentences) == 0 {
+		_, err := fmt.Fprintf(output, "No example sentences for %v\n", word)
+		return err
+	}
+	fmt.Fprintf(output, "Example sentences for %v:\n\n", word)
+	for _, sentence := range sentences {
+		if _, err := fmt.Fprintf(output, "%v\n%v\n\n", sentence.Japanese, sentence.English); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONSentence is the JSON form of an example sentence
+type JSONSentence struct {
+	Japanese string `json:"japanese"`
+	English  string `json:"english"`
+}
+
+func NewJSONSentences(sentences []examples.Sentence) []JSONSentence {
+	converted := []JSONSentence{}
+	for _, sentence := range sentences {
+		converted = append(converted, JSONSentence(sentence))
+	}
+	return converted
+}
+
+func (JSONFormatter) FormatExamples(output io.Writer, word string, sentences []examples.Sentence) error {
+	document := struct {
+		Word     string         `json:"word"`
+		Examples []JSONSentence `json:"examples"`
+	}{word, NewJSONSentences(sentences)}
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+func (TSVFormatter) FormatExamples(output io.Writer, word string, sentences []examples.Sentence) error {
+	if _, err := fmt.Fprintln(output, "word\tjapanese\tenglish"); err != nil {
+		return err
+	}
+	for _, sentence := range sentences {
+		columns := []string{tsvEscape(word), tsvEscape(sentence.Japanese), tsvEscape(sentence.English)}
+		if _, err := fmt.Fprintln(output, strings.Join(columns, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (MarkdownFormatter) FormatExamples(output io.Writer, word string, sentences []examples.Sentence) error {
+	fmt.Fprintf(output, "## Examples for %v\n\n", markdownEscape(word))
+	if len(sentences) == 0 {
+		_, err := fmt.Fprintln(output, "No example sentences")
+		return err
+	}
+	for _, sentence := range sentences {
+		if _, err := fmt.Fprintf(output, "- %v  \n  %v\n", markdownEscape(sentence.Japanese), markdownEscape(sentence.English)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(output, "\n")
+	return err
+}
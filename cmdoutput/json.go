@@ -0,0 +1,149 @@
+package cmdoutput
+
+import (
+	"encoding/json"
+	"io"
+	"japp/env"
+	"japp/wordsearch"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// JSONFormatter writes the results as a single JSON document carrying the complete JMdict entries, meant to be piped into tools like jq
+type JSONFormatter struct{}
+
+type jsonResults struct {
+	Query   string       `json:"query,omitempty"`
+	Total   int          `json:"total"`
+	Offset  int          `json:"offset"`
+	Limit   int          `json:"limit"`
+	Results []JSONResult `json:"results"`
+}
+
+// JSONResult is the JSON form of a single search result, shared with the HTTP server so that both print the same documents
+type JSONResult struct {
+	WordID     int            `json:"wordID"`
+	Score      float64        `json:"score"`
+	Components jsonComponents `json:"scoreComponents"`
+	Hash       []uint16       `json:"hash"`
+	Inflection []string       `json:"inflection,omitempty"`
+	Tier       string         `json:"tier,omitempty"`
+	Common     bool           `json:"common"`
+	Name       bool           `json:"name,omitempty"` // The entry comes from JMnedict, its senses listing the name types in misc
+	Pitch      []JSONPitch    `json:"pitch,omitempty"`
+	Entry      JSONEntry      `json:"entry"`
+	Examples   []JSONSentence `json:"examples,omitempty"`
+}
+
+type jsonComponents struct {
+	Entry  float64 `json:"entry"`
+	Form   float64 `json:"form"`
+	Length float64 `json:"length"`
+}
+
+// JSONEntry is the JSON form of a JMdict entry
+type JSONEntry struct {
+	Sequence int           `json:"sequence"`
+	Kanji    []jsonKanji   `json:"kanji"`
+	Readings []jsonReading `json:"readings"`
+	Senses   []jsonSense   `json:"senses"`
+}
+
+type jsonKanji struct {
+	Expression  string   `json:"expression"`
+	Information []string `json:"information,omitempty"`
+	Priorities  []string `json:"priorities,omitempty"`
+}
+
+type jsonReading struct {
+	Reading      string   `json:"reading"`
+	Restrictions []string `json:"restrictions,omitempty"`
+	Information  []string `json:"information,omitempty"`
+	Priorities   []string `json:"priorities,omitempty"`
+}
+
+type jsonSense struct {
+	RestrictedKanji    []string       `json:"restrictedKanji,omitempty"`
+	RestrictedReadings []string       `json:"restrictedReadings,omitempty"`
+	PartsOfSpeech      []string       `json:"partsOfSpeech,omitempty"`
+	Fields             []string       `json:"fields,omitempty"`
+	Misc               []string       `json:"misc,omitempty"`
+	Dialects           []string       `json:"dialects,omitempty"`
+	Information        []string       `json:"information,omitempty"`
+	References         []string       `json:"references,omitempty"`
+	Antonyms           []string       `json:"antonyms,omitempty"`
+	Glossary           []jsonGlossary `json:"glossary"`
+}
+
+type jsonGlossary struct {
+	Content  string `json:"content"`
+	Language string `json:"language,omitempty"`
+}
+
+func (JSONFormatter) Format(output io.Writer, table env.Environment, page wordsearch.Page, query string) error {
+	document := jsonResults{Query: query, Total: page.Total, Offset: page.Offset, Limit: page.Limit}
+	document.Results = NewJSONResults(table, page)
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// NewJSONResults converts the results of a page into their JSON form, never returning nil so that an empty list is encoded as []
+func NewJSONResults(table env.Environment, page wordsearch.Page) []JSONResult {
+	converted := []JSONResult{}
+	for _, result := range page.Results {
+		converted = append(converted, JSONResult{
+			WordID:     result.Entry.WordID,
+			Score:      result.Score,
+			Components: jsonComponents(result.Components),
+			Hash:       result.Entry.Hash,
+			Inflection: result.Inflection,
+			Tier:       result.Tier.String(),
+			Common:     result.Common,
+			Name:       result.Name,
+			Pitch:      NewJSONPitch(resultPitch(table, result)),
+			Entry:      NewJSONEntry(resultEntry(table, result)),
+			Examples:   NewJSONSentences(resultExamples(table, result, page.ExampleCount())),
+		})
+	}
+	return converted
+}
+
+func NewJSONEntry(entry jmdict.JmdictEntry) JSONEntry {
+	converted := JSONEntry{Sequence: entry.Sequence, Kanji: []jsonKanji{}, Readings: []jsonReading{}, Senses: []jsonSense{}}
+	for _, kanji := range entry.Kanji {
+		converted.Kanji = append(converted.Kanji, jsonKanji{kanji.Expression, kanji.Information, kanji.Priorities})
+	}
+	for _, reading := range entry.Readings {
+		converted.Readings = append(converted.Readings, jsonReading{
+			Reading:      reading.Reading,
+			Restrictions: reading.Restrictions,
+			Information:  reading.Information,
+			Priorities:   reading.Priorities,
+		})
+	}
+	for _, sense := range entry.Sense {
+		converted_sense := jsonSense{
+			RestrictedKanji:    sense.RestrictedKanji,
+			RestrictedReadings: sense.RestrictedReadings,
+			PartsOfSpeech:      sense.PartsOfSpeech,
+			Fields:             sense.Fields,
+			Misc:               sense.Misc,
+			Dialects:           sense.Dialects,
+			Information:        sense.Information,
+			References:         sense.References,
+			Antonyms:           sense.Antonyms,
+			Glossary:           []jsonGlossary{},
+		}
+		for _, gloss := range sense.Glossary {
+			converted_gloss := jsonGlossary{Content: gloss.Content}
+			if gloss.Language != nil {
+				converted_gloss.Language = *gloss.Language
+			}
+			converted_sense.Glossary = append(converted_sense.Glossary, converted_gloss)
+		}
+		converted.Senses = append(converted.Senses, converted_sense)
+	}
+	return converted
+}
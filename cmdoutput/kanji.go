@@ -0,0 +1,123 @@
+package cmdoutput
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"japp/kanjidic"
+	"strings"
+)
+
+// KanjiFormatter is implemented by the formatters that can also render the information about a single kanji
+type KanjiFormatter interface {
+	FormatKanji(output io.Writer, info kanjidic.Info) error
+}
+
+// FormatKanji renders a kanji with the given formatter, falling back to the text layout if the formatter doesn't support kanji
+func FormatKanji(output io.Writer, formatter Formatter, info kanjidic.Info) error {
+	if kanjiFormatter, ok := formatter.(KanjiFormatter); ok {
+		return kanjiFormatter.FormatKanji(output, info)
+	}
+	return TextFormatter{}.FormatKanji(output, info)
+}
+
+func (TextFormatter) FormatKanji(output io.Writer, info kanjidic.Info) error {
+	fmt.Fprintf(output, "Kanji: %v\n", info.Literal)
+	fmt.Fprintf(output, "On readings: %v\n", strings.Join(info.OnReadings, ", "))
+	fmt.Fprintf(output, "Kun readings: %v\n", strings.Join(info.KunReadings, ", "))
+	if len(info.Nanori) != 0 {
+		fmt.Fprintf(output, "Name readings: %v\n", strings.Join(info.Nanori, ", "))
+	}
+	fmt.Fprintf(output, "Meanings: %v\n", strings.Join(info.Meanings, ", "))
+	_, err := fmt.Fprintf(output, "%v\n\n", strings.Join(kanjiStatistics(info), ", "))
+	return err
+}
+
+func (JSONFormatter) FormatKanji(output io.Writer, info kanjidic.Info) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewJSONKanji(info))
+}
+
+var tsvKanjiHeader = []string{"kanji", "on_readings", "kun_readings", "nanori", "meanings", "strokes", "grade", "jlpt", "frequency"}
+
+func (TSVFormatter) FormatKanji(output io.Writer, info kanjidic.Info) error {
+	columns := []string{
+		info.Literal,
+		strings.Join(info.OnReadings, ";"),
+		strings.Join(info.KunReadings, ";"),
+		strings.Join(info.Nanori, ";"),
+		strings.Join(info.Meanings, ";"),
+		fmt.Sprint(info.StrokeCount),
+		fmt.Sprint(info.Grade),
+		fmt.Sprint(info.JLPT),
+		fmt.Sprint(info.Frequency),
+	}
+	for i, column := range columns {
+		columns[i] = tsvEscape(column)
+	}
+	_, err := fmt.Fprintf(output, "%v\n%v\n", strings.Join(tsvKanjiHeader, "\t"), strings.Join(columns, "\t"))
+	return err
+}
+
+func (MarkdownFormatter) FormatKanji(output io.Writer, info kanjidic.Info) error {
+	fmt.Fprintf(output, "## %v\n\n", markdownEscape(info.Literal))
+	fmt.Fprintf(output, "- **On:** %v\n", markdownEscape(strings.Join(info.OnReadings, "、")))
+	fmt.Fprintf(output, "- **Kun:** %v\n", markdownEscape(strings.Join(info.KunReadings, "、")))
+	if len(info.Nanori) != 0 {
+		fmt.Fprintf(output, "- **Nanori:** %v\n", markdownEscape(strings.Join(info.Nanori, "、")))
+	}
+	fmt.Fprintf(output, "- **Meanings:** %v\n", markdownEscape(strings.Join(info.Meanings, "; ")))
+	_, err := fmt.Fprintf(output, "- %v\n\n", strings.Join(kanjiStatistics(info), ", "))
+	return err
+}
+
+// kanjiStatistics describes the numbers known about a kanji, leaving out the ones KANJIDIC2 doesn't give
+func kanjiStatistics(info kanjidic.Info) []string {
+	statistics := []string{fmt.Sprintf("%v strokes", info.StrokeCount)}
+	if info.Grade != 0 {
+		statistics = append(statistics, fmt.Sprintf("grade %v", info.Grade))
+	}
+	if info.JLPT != 0 {
+		statistics = append(statistics, fmt.Sprintf("old JLPT level %v", info.JLPT))
+	}
+	if info.Frequency != 0 {
+		statistics = append(statistics, fmt.Sprintf("frequency rank %v", info.Frequency))
+	}
+	return statistics
+}
+
+// JSONKanji is the JSON form of the information about a single kanji
+type JSONKanji struct {
+	Literal     string   `json:"literal"`
+	OnReadings  []string `json:"onReadings"`
+	KunReadings []string `json:"kunReadings"`
+	Nanori      []string `json:"nanori"`
+	Meanings    []string `json:"meanings"`
+	StrokeCount int      `json:"strokeCount"`
+	Grade       int      `json:"grade,omitempty"`
+	JLPT        int      `json:"jlpt,omitempty"`
+	Frequency   int      `json:"frequency,omitempty"`
+}
+
+func NewJSONKanji(info kanjidic.Info) JSONKanji {
+	return JSONKanji{
+		Literal:     info.Literal,
+		OnReadings:  nonNil(info.OnReadings),
+		KunReadings: nonNil(info.KunReadings),
+		Nanori:      nonNil(info.Nanori),
+		Meanings:    nonNil(info.Meanings),
+		StrokeCount: info.StrokeCount,
+		Grade:       info.Grade,
+		JLPT:        info.JLPT,
+		Frequency:   info.Frequency,
+	}
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,85 @@
+package cmdoutput
+
+import (
+	"fmt"
+	"io"
+	"japp/env"
+	"japp/wordsearch"
+	"strings"
+)
+
+// MarkdownFormatter writes every result as a section with a numbered list of senses, ready to be pasted into documentation
+type MarkdownFormatter struct{}
+
+func (MarkdownFormatter) Format(output io.Writer, table env.Environment, page wordsearch.Page, query string) error {
+	if query != "" {
+		fmt.Fprintf(output, "## %v\n\n", markdownEscape(query))
+	}
+	if page.Total == 0 {
+		_, err := fmt.Fprintln(output, "No results")
+		return err
+	}
+	for _, result := range page.Results {
+		entry := resultEntry(table, result)
+		var kanji, readings []string
+		for _, k := range entry.Kanji {
+			kanji = append(kanji, markdownEscape(k.Expression))
+		}
+		for _, r := range entry.Readings {
+			readings = append(readings, markdownEscape(r.Reading))
+		}
+		if len(kanji) != 0 {
+			fmt.Fprintf(output, "### %v 【%v】\n\n", strings.Join(kanji, "、"), strings.Join(readings, "、"))
+		} else {
+			fmt.Fprintf(output, "### %v\n\n", strings.Join(readings, "、"))
+		}
+		if result.Name {
+			fmt.Fprintf(output, "*Name: %v*\n\n", markdownEscape(strings.Join(nameTypes(entry), ", ")))
+		}
+		if accents := resultPitch(table, result); len(accents) != 0 {
+			fmt.Fprintf(output, "*Pitch: %v*\n\n", markdownEscape(strings.Join(pitchStrings(accents), ", ")))
+		}
+		if len(result.Inflection) != 0 {
+			fmt.Fprintf(output, "*Inflection: %v*\n\n", strings.Join(result.Inflection, " → "))
+		}
+		for i, sense := range entry.Sense {
+			var tags []string
+			tags = append(tags, sense.PartsOfSpeech...)
+			tags = append(tags, sense.Fields...)
+			tags = append(tags, sense.Misc...)
+			tags = append(tags, sense.Dialects...)
+			fmt.Fprintf(output, "%v. ", i+1)
+			if len(tags) != 0 {
+				fmt.Fprintf(output, "*(%v)* ", markdownEscape(strings.Join(tags, "; ")))
+			}
+			fmt.Fprintf(output, "%v", markdownEscape(joinGlosses(sense.Glossary, "; ")))
+			if len(sense.Information) != 0 {
+				fmt.Fprintf(output, " — %v", markdownEscape(strings.Join(sense.Information, "; ")))
+			}
+			if len(sense.References) != 0 {
+				fmt.Fprintf(output, " (see also: %v)", markdownEscape(strings.Join(sense.References, ", ")))
+			}
+			if len(sense.Antonyms) != 0 {
+				fmt.Fprintf(output, " (antonyms: %v)", markdownEscape(strings.Join(sense.Antonyms, ", ")))
+			}
+			fmt.Fprintf(output, "\n")
+		}
+		for _, sentence := range resultExamples(table, result, page.ExampleCount()) {
+			fmt.Fprintf(output, "\n> %v  \n> %v\n", markdownEscape(sentence.Japanese), markdownEscape(sentence.English))
+		}
+		if _, err := fmt.Fprintf(output, "\n"); err != nil {
+			return err
+		}
+	}
+	if page.HasNext() || page.HasPrevious() {
+		_, err := fmt.Fprintf(output, "*%v*\n", pageSummary(page))
+		return err
+	}
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "#", `\#`, "|", `\|`)
+
+func markdownEscape(text string) string {
+	return markdownEscaper.Replace(text)
+}
@@ -0,0 +1,46 @@
+package cmdoutput
+
+import (
+	"japp/env"
+	"japp/pitch"
+	"japp/wordsearch"
+)
+
+// resultPitch finds the accents of the readings of a result, names having none
+func resultPitch(table env.Environment, result wordsearch.ResultEntry) []pitch.Pattern {
+	if result.Name {
+		return nil
+	}
+	entry := resultEntry(table, result)
+	var forms, readings []string
+	for _, kanji := range entry.Kanji {
+		forms = append(forms, kanji.Expression)
+	}
+	for _, reading := range entry.Readings {
+		readings = append(readings, reading.Reading)
+	}
+	return table.Accents.Lookup(forms, readings)
+}
+
+func pitchStrings(patterns []pitch.Pattern) []string {
+	var described []string
+	for _, pattern := range patterns {
+		described = append(described, pattern.String())
+	}
+	return described
+}
+
+// JSONPitch is the JSON form of the accent of a reading
+type JSONPitch struct {
+	Reading  string `json:"reading"`
+	Downstep int    `json:"downstep"`
+	Contour  string `json:"contour"`
+}
+
+func NewJSONPitch(patterns []pitch.Pattern) []JSONPitch {
+	var converted []JSONPitch
+	for _, pattern := range patterns {
+		converted = append(converted, JSONPitch{pattern.Reading, pattern.Downstep, pattern.Contour()})
+	}
+	return converted
+}
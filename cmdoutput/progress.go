@@ -0,0 +1,20 @@
+package cmdoutput
+
+import (
+	"fmt"
+	"io"
+)
+
+// ProgressPrinter shows the progress of a build on a single terminal line, which is cleared once the build is over
+// It fits env.BuildProgress
+func ProgressPrinter(output io.Writer) func(stage string, done, total int) {
+	return func(stage string, done, total int) {
+		if stage == "" {
+			fmt.Fprintf(output, "\r\033[K")
+		} else if total == 0 {
+			fmt.Fprintf(output, "\r\033[K%v...", stage)
+		} else {
+			fmt.Fprintf(output, "\r\033[K%v: %v%% (%v of %v entries)", stage, done*100/total, done, total)
+		}
+	}
+}
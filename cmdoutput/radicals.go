@@ -0,0 +1,38 @@
+package cmdoutput
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PrintRadicalSearch lists the kanji found by their radicals grouped by stroke count, each with the number used to pick it,
+// followed by the radicals that would narrow the list down further
+func PrintRadicalSearch(output io.Writer, kanji []string, strokes map[string]int, remaining []string) error {
+	if len(kanji) == 0 {
+		_, err := fmt.Fprintln(output, "No kanji contain all of these radicals")
+		return err
+	}
+	fmt.Fprintf(output, "Kanji with these radicals (%v):\n", len(kanji))
+	group := -1
+	for i, character := range kanji {
+		if count := strokes[character]; count != group {
+			if i != 0 {
+				fmt.Fprintf(output, "\n")
+			}
+			group = count
+			if count == 0 {
+				fmt.Fprintf(output, "  ? strokes:")
+			} else {
+				fmt.Fprintf(output, "%3v strokes:", count)
+			}
+		}
+		fmt.Fprintf(output, " [%v]%v", i+1, character)
+	}
+	fmt.Fprintf(output, "\n")
+	if len(remaining) != 0 {
+		fmt.Fprintf(output, "Radicals that narrow the list down: %v\n", strings.Join(remaining, " "))
+	}
+	_, err := fmt.Fprintf(output, "\n")
+	return err
+}
@@ -0,0 +1,106 @@
+package cmdoutput
+
+import (
+	"fmt"
+	"io"
+	"japp/env"
+	"japp/wordsearch"
+	"strings"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// TSVFormatter writes one line per result with a header line on top, lists inside a column are separated by semicolons and senses by " | "
+// New columns go at the end of tsvHeader, so that scripts reading the columns by position keep working
+type TSVFormatter struct{}
+
+var tsvHeader = []string{"word_id", "dictionary", "score", "tier", "common", "kanji", "readings", "pitch", "parts_of_speech", "misc", "fields", "dialects", "priorities", "inflection", "glosses", "references", "antonyms"}
+
+func (TSVFormatter) Format(output io.Writer, table env.Environment, page wordsearch.Page, query string) error {
+	if _, err := fmt.Fprintln(output, strings.Join(tsvHeader, "\t")); err != nil {
+		return err
+	}
+	for _, result := range page.Results {
+		entry := resultEntry(table, result)
+		var kanji, readings, priorities, pos, misc, fields, dialects, senses, references, antonyms []string
+		for _, k := range entry.Kanji {
+			kanji = append(kanji, k.Expression)
+			priorities = append(priorities, k.Priorities...)
+		}
+		for _, r := range entry.Readings {
+			readings = append(readings, r.Reading)
+			priorities = append(priorities, r.Priorities...)
+		}
+		for _, sense := range entry.Sense {
+			pos = appendUnique(pos, sense.PartsOfSpeech...)
+			misc = appendUnique(misc, sense.Misc...)
+			fields = appendUnique(fields, sense.Fields...)
+			dialects = appendUnique(dialects, sense.Dialects...)
+			senses = append(senses, joinGlosses(sense.Glossary, "; "))
+			references = appendUnique(references, sense.References...)
+			antonyms = appendUnique(antonyms, sense.Antonyms...)
+		}
+		columns := []string{
+			fmt.Sprint(result.Entry.WordID),
+			dictionaryName(result.Name),
+			fmt.Sprint(result.Score),
+			result.Tier.String(),
+			fmt.Sprint(result.Common),
+			strings.Join(kanji, ";"),
+			strings.Join(readings, ";"),
+			strings.Join(pitchStrings(resultPitch(table, result)), ";"),
+			strings.Join(pos, ";"),
+			strings.Join(misc, ";"),
+			strings.Join(fields, ";"),
+			strings.Join(dialects, ";"),
+			strings.Join(appendUnique(nil, priorities...), ";"),
+			strings.Join(result.Inflection, ";"),
+			strings.Join(senses, " | "),
+			strings.Join(references, ";"),
+			strings.Join(antonyms, ";"),
+		}
+		for i, column := range columns {
+			columns[i] = tsvEscape(column)
+		}
+		if _, err := fmt.Fprintln(output, strings.Join(columns, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dictionaryName(name bool) string {
+	if name {
+		return "names"
+	}
+	return "words"
+}
+
+// Tabs and line breaks would break the columns apart, so they are replaced by spaces
+func tsvEscape(column string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(column)
+}
+
+func joinGlosses(glossary []jmdict.JmdictGlossary, separator string) string {
+	var glosses []string
+	for _, gloss := range glossary {
+		glosses = append(glosses, gloss.Content)
+	}
+	return strings.Join(glosses, separator)
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,62 @@
+package cmdoutput
+
+import (
+	"bytes"
+	"japp/env"
+	"japp/searchgrids"
+	"japp/wordsearch"
+	"strings"
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// The header is read by position in scripts, so existing columns never move
+func TestTSVHeader(t *testing.T) {
+	expected := "word_id\tdictionary\tscore\ttier\tcommon\tkanji\treadings\tpitch\tparts_of_speech\tmisc\tfields\tdialects\tpriorities\tinflection\tglosses\treferences\tantonyms"
+	var output bytes.Buffer
+	if err := (TSVFormatter{}).Format(&output, env.Environment{}, wordsearch.Page{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if header := strings.TrimSuffix(output.String(), "\n"); header != expected {
+		t.Errorf("the header is\n%q, expected\n%q", header, expected)
+	}
+}
+
+func TestTSVReferencesAndAntonyms(t *testing.T) {
+	var table env.Environment
+	table.Dict = &jmdict.Jmdict{Entries: []jmdict.JmdictEntry{{
+		Kanji:    []jmdict.JmdictKanji{{Expression: "高い"}},
+		Readings: []jmdict.JmdictReading{{Reading: "たかい"}},
+		Sense: []jmdict.JmdictSense{
+			{Glossary: []jmdict.JmdictGlossary{{Content: "high"}}, References: []string{"高さ"}, Antonyms: []string{"低い"}},
+			{Glossary: []jmdict.JmdictGlossary{{Content: "expensive\tcostly"}}, References: []string{"値段", "高さ"}, Antonyms: []string{"安い"}},
+		},
+	}}}
+	page := wordsearch.Page{Total: 1, Results: wordsearch.ResultEntries{{Entry: searchgrids.Entry{WordID: 0}}}}
+	var output bytes.Buffer
+	if err := (TSVFormatter{}).Format(&output, table, page, "高い"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("the output has %v lines, expected the header and one result", len(lines))
+	}
+	columns := map[string]string{}
+	values := strings.Split(lines[1], "\t")
+	if len(values) != len(tsvHeader) {
+		t.Fatalf("the result has %v columns and the header %v: %q", len(values), len(tsvHeader), lines[1])
+	}
+	for i, name := range tsvHeader {
+		columns[name] = values[i]
+	}
+	for name, expected := range map[string]string{
+		"glosses":    "high | expensive costly",
+		"references": "高さ;値段",
+		"antonyms":   "低い;安い",
+	} {
+		if columns[name] != expected {
+			t.Errorf("the %v column is %q, expected %q", name, columns[name], expected)
+		}
+	}
+}
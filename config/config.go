@@ -0,0 +1,190 @@
+package config
+
+// This package finds the files of the dictionary. Every setting can come, from the weakest to the strongest, from the defaults,
+// the config file ($XDG_CONFIG_HOME/japp/config), the JAPP_* environment variables or the command line flags
+// The config file holds lines like "data_dir = /usr/share/japp", blank lines and lines starting with # being ignored
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds the data directory, where the source files are looked for, the cache directory, where the envfile is written,
+// and the path of every source file, which defaults to its usual name inside the data directory
+type Config struct {
+	DataDir  string
+	CacheDir string
+	JMdict   string
+	Kanjidic string
+	Radkfile string
+	Kradfile string
+	JMnedict string
+	Examples string
+	Accents  string
+}
+
+// Setting describes one setting: its key in the config file, its flag, and the file name it defaults to inside the data directory for source files
+type Setting struct {
+	Key   string
+	Usage string
+	file  string
+	field func(config *Config) *string
+}
+
+// Settings lists every setting in the order they are documented
+var Settings = []Setting{
+	{"data_dir", "directory holding the dictionary files", "", func(config *Config) *string { return &config.DataDir }},
+	{"cache_dir", "directory where the parsed dictionaries are cached", "", func(config *Config) *string { return &config.CacheDir }},
+	{"jmdict", "path of JMdict_e", "JMdict_e", func(config *Config) *string { return &config.JMdict }},
+	{"kanjidic", "path of kanjidic2.xml", "kanjidic2.xml", func(config *Config) *string { return &config.Kanjidic }},
+	{"radkfile", "path of the UTF-8 radkfile", "radkfile", func(config *Config) *string { return &config.Radkfile }},
+	{"kradfile", "path of the UTF-8 kradfile", "kradfile", func(config *Config) *string { return &config.Kradfile }},
+	{"jmnedict", "path of JMnedict.xml", "JMnedict.xml", func(config *Config) *string { return &config.JMnedict }},
+	{"examples", "path of examples.utf", "examples.utf", func(config *Config) *string { return &config.Examples }},
+	{"accents", "path of the pitch accents TSV", "accents.txt", func(config *Config) *string { return &config.Accents }},
+}
+
+// Flag is the command line flag of the setting, e.g. --data-dir
+func (setting Setting) Flag() string {
+	return strings.ReplaceAll(setting.Key, "_", "-")
+}
+
+// Variable is the environment variable of the setting, e.g. JAPP_DATA_DIR
+func (setting Setting) Variable() string {
+	return "JAPP_" + strings.ToUpper(setting.Key)
+}
+
+// Field gives access to the value of the setting in a config
+func (setting Setting) Field(config *Config) *string {
+	return setting.field(config)
+}
+
+// legacyDir is where the files were kept before the XDG directories were used, relative to the working directory
+const legacyDir = "env"
+
+// Load combines the config file, the environment variables and the overrides, which come from the command line and win over everything else
+// The config file is the given path if there is one, $JAPP_CONFIG if set, and $XDG_CONFIG_HOME/japp/config otherwise, which may be missing
+func Load(path string, overrides Config) (Config, error) {
+	var loaded Config
+	explicit := path != ""
+	if !explicit {
+		path, explicit = os.LookupEnv("JAPP_CONFIG")
+	}
+	if !explicit || path == "" {
+		path, explicit = filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "japp", "config"), false
+	}
+	if err := loaded.readFile(path, explicit); err != nil {
+		return Config{}, err
+	}
+	for _, setting := range Settings {
+		if value, found := os.LookupEnv(setting.Variable()); found && value != "" {
+			*setting.Field(&loaded) = value
+		}
+		if value := *setting.Field(&overrides); value != "" {
+			*setting.Field(&loaded) = value
+		}
+	}
+	loaded.fillDefaults()
+	return loaded, nil
+}
+
+// Default is the configuration used when none was loaded
+func Default() Config {
+	var defaults Config
+	defaults.fillDefaults()
+	return defaults
+}
+
+func (config *Config) readFile(path string, required bool) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) && !required {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("config file open: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return fmt.Errorf("%v:%v: expected key = value", path, line_number)
+		}
+		setting, known := lookup(strings.TrimSpace(key))
+		if !known {
+			return fmt.Errorf("%v:%v: unknown setting %q", path, line_number, strings.TrimSpace(key))
+		}
+		*setting.Field(config) = expandHome(strings.TrimSpace(value))
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("config file read: %w", err)
+	}
+	return nil
+}
+
+func lookup(key string) (Setting, bool) {
+	for _, setting := range Settings {
+		if setting.Key == key {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// fillDefaults puts the data in $XDG_DATA_HOME/japp and the cache in $XDG_CACHE_HOME/japp
+// Installs that keep JMdict_e in the env folder of the working directory, as the program used to require, go on working as before
+func (config *Config) fillDefaults() {
+	if config.DataDir == "" {
+		config.DataDir = filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), "japp")
+		if !exists(filepath.Join(config.DataDir, "JMdict_e")) && exists(filepath.Join(legacyDir, "JMdict_e")) {
+			config.DataDir = legacyDir
+			if config.CacheDir == "" {
+				config.CacheDir = legacyDir
+			}
+		}
+	}
+	if config.CacheDir == "" {
+		config.CacheDir = filepath.Join(xdgDir("XDG_CACHE_HOME", ".cache"), "japp")
+	}
+	for _, setting := range Settings {
+		if field := setting.Field(config); setting.file != "" && *field == "" {
+			*field = filepath.Join(config.DataDir, setting.file)
+		}
+	}
+}
+
+// xdgDir reads an XDG base directory variable, falling back to its default under the home directory as the specification says
+func xdgDir(variable, fallback string) string {
+	if dir := os.Getenv(variable); filepath.IsAbs(dir) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fallback
+	}
+	return filepath.Join(home, fallback)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
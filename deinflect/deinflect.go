@@ -0,0 +1,96 @@
+package deinflect
+
+// This package turns conjugated verbs and adjectives (食べました, 高くない) back into the dictionary forms that JMdict indexes
+// It works on suffixes only, so the same rules apply to words written in kanji with okurigana and to words written in kana
+
+import (
+	"strings"
+)
+
+// Type is a bit set of the word classes an inflected form can be derived from
+type Type uint16
+
+const (
+	TypeV1   Type = 1 << iota // Ichidan verb (食べる)
+	TypeV5                    // Godan verb (書く)
+	TypeVK                    // The irregular verb くる
+	TypeVS                    // The irregular verb する and nouns taking it
+	TypeAdjI                  // I-adjective (高い), which is also how ない and たい forms inflect further
+	TypeIru                   // The ている auxiliary, only used while chaining rules
+)
+
+// Rule replaces the suffix From of an inflected form with To
+// In is the class the inflected form must belong to for the rule to apply (0 means the form can only be the outermost one), Out is the class of the result
+type Rule struct {
+	From   string
+	To     string
+	In     Type
+	Out    Type
+	Reason string
+}
+
+// Candidate is a possible dictionary form of the searched word, with the inflections that lead to it listed from the dictionary form outwards
+type Candidate struct {
+	Word    string
+	Type    Type
+	Reasons []string
+}
+
+// Every chain is capped to avoid looping forever on pathological input
+const maxChain = 6
+
+// Deinflect returns every dictionary form the word could have been conjugated from, without the word itself
+// The candidates are only guesses based on the endings, so each of them has to be checked against the dictionary with MatchesPartOfSpeech
+func Deinflect(word string) []Candidate {
+	var candidates []Candidate
+	seen := map[string]bool{}
+	queue := []Candidate{{Word: word}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if len(current.Reasons) >= maxChain {
+			continue
+		}
+		for _, rule := range rules {
+			if !strings.HasSuffix(current.Word, rule.From) {
+				continue
+			}
+			if len(current.Reasons) != 0 && current.Type&rule.In == 0 {
+				continue
+			}
+			stem := strings.TrimSuffix(current.Word, rule.From)
+			if stem == "" && rule.To == "" {
+				continue
+			}
+			var candidate Candidate
+			candidate.Word = stem + rule.To
+			candidate.Type = rule.Out
+			candidate.Reasons = append([]string{rule.Reason}, current.Reasons...)
+			key := candidate.Word + "/" + strings.Join(candidate.Reasons, "/")
+			if seen[key] || candidate.Word == word {
+				continue
+			}
+			seen[key] = true
+			candidates = append(candidates, candidate)
+			queue = append(queue, candidate)
+		}
+	}
+	return candidates
+}
+
+// MatchesPartOfSpeech checks a candidate's class against a JMdict part of speech, which may come either as the entity name (v5k) or as its expansion (Godan verb with 'ku' ending)
+func MatchesPartOfSpeech(class Type, pos string) bool {
+	switch {
+	case class&TypeV1 != 0 && (strings.HasPrefix(pos, "v1") || strings.HasPrefix(pos, "Ichidan verb")):
+		return true
+	case class&TypeV5 != 0 && (strings.HasPrefix(pos, "v5") || strings.HasPrefix(pos, "Godan verb")):
+		return true
+	case class&TypeVK != 0 && (pos == "vk" || strings.HasPrefix(pos, "Kuru verb")):
+		return true
+	case class&TypeVS != 0 && (strings.HasPrefix(pos, "vs") || strings.Contains(pos, "suru")):
+		return true
+	case class&TypeAdjI != 0 && (strings.HasPrefix(pos, "adj-i") || strings.HasPrefix(pos, "adjective (keiyoushi)")):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package deinflect
+
+import (
+	"strings"
+	"testing"
+)
+
+// find returns the candidate with the given word whose type overlaps class
+func find(candidates []Candidate, word string, class Type) (Candidate, bool) {
+	for _, candidate := range candidates {
+		if candidate.Word == word && candidate.Type&class != 0 {
+			return candidate, true
+		}
+	}
+	return Candidate{}, false
+}
+
+func TestDeinflect(t *testing.T) {
+	tests := []struct {
+		word    string
+		base    string
+		class   Type
+		reasons string
+	}{
+		{"食べました", "食べる", TypeV1, "polite past"},
+		{"高くない", "高い", TypeAdjI, "negative"},
+		{"行って", "行く", TypeV5, "te"},
+		{"たべています", "たべる", TypeV1, "te progressive polite"},
+		{"書いた", "書く", TypeV5, "past"},
+		{"勉強した", "勉強する", TypeVS, "past"},
+	}
+	for _, test := range tests {
+		candidate, found := find(Deinflect(test.word), test.base, test.class)
+		if !found {
+			t.Errorf("Deinflect(%q) doesn't give %q", test.word, test.base)
+		} else if reasons := strings.Join(candidate.Reasons, " "); reasons != test.reasons {
+			t.Errorf("Deinflect(%q) gives %q through %q, expected %q", test.word, test.base, reasons, test.reasons)
+		}
+	}
+}
+
+func TestDeinflectFalsePositives(t *testing.T) {
+	for _, word := range []string{"猫", "ねこ", "見る", "水道"} {
+		if candidates := Deinflect(word); len(candidates) != 0 {
+			t.Errorf("Deinflect(%q) = %v, expected no candidates", word, candidates)
+		}
+	}
+	if _, found := find(Deinflect("高くない"), "高い", TypeV1|TypeV5|TypeVK|TypeVS); found {
+		t.Errorf("高くない was deinflected into 高い as a verb")
+	}
+	if _, found := find(Deinflect("行って"), "行く", TypeV1|TypeAdjI); found {
+		t.Errorf("行って was deinflected into 行く as something other than a godan verb")
+	}
+}
+
+func TestMatchesPartOfSpeech(t *testing.T) {
+	tests := []struct {
+		class   Type
+		pos     string
+		matches bool
+	}{
+		{TypeV1, "v1", true},
+		{TypeV1, "Ichidan verb", true},
+		{TypeV5, "v5k", true},
+		{TypeV5, "Godan verb with 'ku' ending", true},
+		{TypeVS, "noun or participle which takes the aux. verb suru", true},
+		{TypeAdjI, "adjective (keiyoushi)", true},
+		{TypeV1, "Godan verb with 'ru' ending", false},
+		{TypeV5, "v1", false},
+		{TypeAdjI, "adjectival nouns or quasi-adjectives (keiyodoshi)", false},
+		{TypeAdjI, "noun (common) (futsuumeishi)", false},
+		{TypeVK, "v5k", false},
+	}
+	for _, test := range tests {
+		if matches := MatchesPartOfSpeech(test.class, test.pos); matches != test.matches {
+			t.Errorf("MatchesPartOfSpeech(%v, %q) = %v, expected %v", test.class, test.pos, matches, test.matches)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package deinflect
+
+// The rule table is generated once from the conjugation patterns of each word class
+// Godan verbs get one rule per dictionary ending, taken from the kana rows below
+
+type godanRow struct {
+	u, i, a, e, o, te, ta string
+}
+
+var godanRows = []godanRow{
+	{"う", "い", "わ", "え", "お", "って", "った"},
+	{"く", "き", "か", "け", "こ", "いて", "いた"},
+	{"ぐ", "ぎ", "が", "げ", "ご", "いで", "いだ"},
+	{"す", "し", "さ", "せ", "そ", "して", "した"},
+	{"つ", "ち", "た", "て", "と", "って", "った"},
+	{"ぬ", "に", "な", "ね", "の", "んで", "んだ"},
+	{"ぶ", "び", "ば", "べ", "ぼ", "んで", "んだ"},
+	{"む", "み", "ま", "め", "も", "んで", "んだ"},
+	{"る", "り", "ら", "れ", "ろ", "って", "った"},
+}
+
+// Polite endings attach to the masu stem of every verb class
+var politeEndings = []struct {
+	suffix, reason string
+}{
+	{"ます", "polite"},
+	{"ました", "polite past"},
+	{"ません", "polite negative"},
+	{"ませんでした", "polite past negative"},
+	{"ましょう", "polite volitional"},
+	{"まして", "polite te"},
+}
+
+// The stems of the irregular verbs, keyed by the form they take before each ending
+// くる is listed with both its kana and its kanji spelling, since the reading of 来 changes between forms
+type irregularStems struct {
+	dictionary, masu, nai, te, ba, volitional, causative, passive string
+}
+
+var irregularVerbs = []struct {
+	class Type
+	stems irregularStems
+}{
+	{TypeVK, irregularStems{"くる", "き", "こ", "き", "くれ", "こよ", "こさせ", "こられ"}},
+	{TypeVK, irregularStems{"来る", "来", "来", "来", "来れ", "来よ", "来させ", "来られ"}},
+	{TypeVS, irregularStems{"する", "し", "し", "し", "すれ", "しよ", "させ", "され"}},
+}
+
+var rules = buildRules()
+
+func buildRules() []Rule {
+	var table []Rule
+	add := func(from, to string, in, out Type, reason string) {
+		table = append(table, Rule{From: from, To: to, In: in, Out: out, Reason: reason})
+	}
+
+	// Ichidan verbs just drop the final る
+	for _, polite := range politeEndings {
+		add(polite.suffix, "る", 0, TypeV1, polite.reason)
+	}
+	add("ない", "る", TypeAdjI, TypeV1, "negative")
+	add("た", "る", 0, TypeV1, "past")
+	add("て", "る", TypeIru, TypeV1, "te")
+	add("たら", "る", 0, TypeV1, "conditional")
+	add("れば", "る", 0, TypeV1, "provisional")
+	add("よう", "る", 0, TypeV1, "volitional")
+	add("ろ", "る", 0, TypeV1, "imperative")
+	add("よ", "る", 0, TypeV1, "imperative")
+	add("たい", "る", TypeAdjI, TypeV1, "desiderative")
+	add("られる", "る", TypeV1, TypeV1, "potential or passive")
+	add("れる", "る", TypeV1, TypeV1, "potential")
+	add("させる", "る", TypeV1, TypeV1, "causative")
+	add("ず", "る", 0, TypeV1, "negative")
+
+	// Godan verbs change the vowel of their last syllable
+	for _, row := range godanRows {
+		for _, polite := range politeEndings {
+			add(row.i+polite.suffix, row.u, 0, TypeV5, polite.reason)
+		}
+		add(row.a+"ない", row.u, TypeAdjI, TypeV5, "negative")
+		add(row.ta, row.u, 0, TypeV5, "past")
+		add(row.te, row.u, TypeIru, TypeV5, "te")
+		add(row.ta+"ら", row.u, 0, TypeV5, "conditional")
+		add(row.e+"ば", row.u, 0, TypeV5, "provisional")
+		add(row.o+"う", row.u, 0, TypeV5, "volitional")
+		add(row.e, row.u, 0, TypeV5, "imperative")
+		add(row.i+"たい", row.u, TypeAdjI, TypeV5, "desiderative")
+		add(row.e+"る", row.u, TypeV1, TypeV5, "potential")
+		add(row.a+"れる", row.u, TypeV1, TypeV5, "passive")
+		add(row.a+"せる", row.u, TypeV1, TypeV5, "causative")
+		add(row.a+"ず", row.u, 0, TypeV5, "negative")
+		add(row.i+"すぎる", row.u, TypeV1, TypeV5, "excess")
+	}
+	// 行く is the only godan verb with an irregular te and past form
+	add("って", "く", TypeIru, TypeV5, "te")
+	add("った", "く", 0, TypeV5, "past")
+
+	for _, irregular := range irregularVerbs {
+		stems := irregular.stems
+		for _, polite := range politeEndings {
+			add(stems.masu+polite.suffix, stems.dictionary, 0, irregular.class, polite.reason)
+		}
+		add(stems.nai+"ない", stems.dictionary, TypeAdjI, irregular.class, "negative")
+		add(stems.te+"た", stems.dictionary, 0, irregular.class, "past")
+		add(stems.te+"て", stems.dictionary, TypeIru, irregular.class, "te")
+		add(stems.te+"たら", stems.dictionary, 0, irregular.class, "conditional")
+		add(stems.ba+"ば", stems.dictionary, 0, irregular.class, "provisional")
+		add(stems.volitional+"う", stems.dictionary, 0, irregular.class, "volitional")
+		add(stems.masu+"たい", stems.dictionary, TypeAdjI, irregular.class, "desiderative")
+		add(stems.causative+"る", stems.dictionary, TypeV1, irregular.class, "causative")
+		add(stems.passive+"る", stems.dictionary, TypeV1, irregular.class, "passive")
+	}
+	add("しろ", "する", 0, TypeVS, "imperative")
+	add("こい", "くる", 0, TypeVK, "imperative")
+	// Nouns taking する (勉強する) are listed without it in JMdict
+	add("する", "", TypeVS, TypeVS, "suru verb")
+
+	// The progressive ている behaves like an ichidan verb and is often contracted to てる
+	add("ている", "て", TypeV1, TypeIru, "progressive")
+	add("てる", "て", TypeV1, TypeIru, "progressive")
+	add("でいる", "で", TypeV1, TypeIru, "progressive")
+	add("でる", "で", TypeV1, TypeIru, "progressive")
+
+	// I-adjectives inflect their final い
+	add("くない", "い", TypeAdjI, TypeAdjI, "negative")
+	add("かった", "い", 0, TypeAdjI, "past")
+	add("くて", "い", 0, TypeAdjI, "te")
+	add("く", "い", 0, TypeAdjI, "adverbial")
+	add("ければ", "い", 0, TypeAdjI, "provisional")
+	add("かったら", "い", 0, TypeAdjI, "conditional")
+	add("さ", "い", 0, TypeAdjI, "noun")
+	add("そう", "い", 0, TypeAdjI, "seemingly")
+	add("すぎる", "い", TypeV1, TypeAdjI, "excess")
+	add("すぎる", "る", TypeV1, TypeV1, "excess")
+	add("くありません", "い", 0, TypeAdjI, "polite negative")
+	add("くありませんでした", "い", 0, TypeAdjI, "polite past negative")
+	add("です", "", 0, TypeAdjI, "polite")
+
+	return table
+}
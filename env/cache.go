@@ -0,0 +1,278 @@
+package env
+
+// The environment is cached in three files of the cache directory: the grids, in the binary format of the searchgrids package, the envfile holding the rest,
+// and a small header telling which version of the program wrote them and from which files
+// The cache is rebuilt whenever the header doesn't match, e.g. after JMdict_e was updated, an optional file was added or the Environment struct changed
+// The header has a file of its own so that it can be updated without writing the whole environment again, see fresh
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"japp/searchgrids"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// formatVersion has to be increased whenever Environment or any type it holds changes, since gob would decode an older file into partly empty data,
+// and whenever the grids index words differently, since an older file would then answer queries at the wrong positions
+const formatVersion = 8
+
+func envfilePath() string {
+	return filepath.Join(paths.CacheDir, "envfile")
+}
+
+func headerPath() string {
+	return filepath.Join(paths.CacheDir, "header")
+}
+
+func gridsPath() string {
+	return filepath.Join(paths.CacheDir, "grids")
+}
+
+var errStale = errors.New("the environment file is out of date")
+
+type header struct {
+	Version int
+	Built   time.Time
+	Sources []source
+}
+
+// source fingerprints a file the environment is built from, a missing file having a size of -1
+type source struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Hash    string
+}
+
+// sourcePaths lists every file the environment is built from, the optional ones included so that downloading one triggers a rebuild
+func sourcePaths() []string {
+	return []string{paths.JMdict, paths.Kanjidic, paths.Radkfile, paths.Kradfile, paths.JMnedict, paths.Examples, paths.Accents}
+}
+
+func newHeader() (header, error) {
+	current := header{Version: formatVersion, Built: time.Now()}
+	for _, path := range sourcePaths() {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			current.Sources = append(current.Sources, source{Path: path, Size: -1})
+			continue
+		} else if err != nil {
+			return header{}, fmt.Errorf("source file stat: %w", err)
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return header{}, err
+		}
+		current.Sources = append(current.Sources, source{Path: path, Size: info.Size(), ModTime: info.ModTime(), Hash: hash})
+	}
+	return current, nil
+}
+
+// fresh tells whether the environment described by the header can still be used
+// Files are only hashed again when their modification time changed, so that a touched but identical file doesn't trigger a rebuild
+// The header it returns records the new modification times of such files, so that they aren't hashed again on the next start
+func (cached header) fresh() (header, bool) {
+	if cached.Version != formatVersion || len(cached.Sources) != len(sourcePaths()) {
+		return cached, false
+	}
+	refreshed := cached
+	refreshed.Sources = append([]source{}, cached.Sources...)
+	for i, path := range sourcePaths() {
+		recorded := cached.Sources[i]
+		info, err := os.Stat(path)
+		if recorded.Path != path {
+			return cached, false
+		} else if err != nil {
+			if recorded.Size != -1 {
+				return cached, false
+			}
+			continue
+		}
+		if info.Size() != recorded.Size {
+			return cached, false
+		} else if info.ModTime().Equal(recorded.ModTime) {
+			continue
+		}
+		if hash, err := hashFile(path); err != nil || hash != recorded.Hash {
+			return cached, false
+		}
+		refreshed.Sources[i].ModTime = info.ModTime()
+	}
+	return refreshed, true
+}
+
+// touched tells whether the header records other modification times than the given one
+func (cached header) touched(other header) bool {
+	for i := range cached.Sources {
+		if !cached.Sources[i].ModTime.Equal(other.Sources[i].ModTime) {
+			return true
+		}
+	}
+	return false
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("source file open: %w", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("source file read: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readCache decodes the header, and the rest only if the header shows that it is up to date
+// The grids are mapped from their own file instead of being decoded, the build ID telling that the grid file and the envfile were written along with the header
+// Decoding the envfile is nearly all the time a start takes, see BenchmarkReadCache
+func readCache() (*Environment, error) {
+	cached, err := readHeader()
+	if err != nil {
+		return nil, err
+	}
+	refreshed, fresh := cached.fresh()
+	if !fresh {
+		return nil, errStale
+	}
+	data, err := mapFile(gridsPath())
+	if err != nil {
+		return nil, fmt.Errorf("grid file map: %w", err)
+	}
+	build, grids, err := searchgrids.LoadGrids(data)
+	if err != nil {
+		return nil, fmt.Errorf("grid file open: %w", err)
+	} else if build != cached.Built.UnixNano() {
+		return nil, errStale
+	}
+	envfile, err := os.Open(envfilePath())
+	if err != nil {
+		return nil, fmt.Errorf("env file open: %w", err)
+	}
+	defer envfile.Close()
+	decoder := gob.NewDecoder(bufio.NewReader(envfile))
+	if err := decoder.Decode(&build); err != nil {
+		return nil, fmt.Errorf("env file decode: %w", err)
+	} else if build != cached.Built.UnixNano() {
+		return nil, errStale
+	}
+	var env Environment
+	if err := decoder.Decode(&env); err != nil {
+		return nil, fmt.Errorf("env decode: %w", err)
+	}
+	if err := env.attachGrids(grids); err != nil {
+		return nil, err
+	}
+	if refreshed.touched(cached) {
+		writeHeader(refreshed) // If this fails, the touched files are only hashed again on the next start
+	}
+	return &env, nil
+}
+
+func readHeader() (header, error) {
+	file, err := os.Open(headerPath())
+	if err != nil {
+		return header{}, fmt.Errorf("env header open: %w", err)
+	}
+	defer file.Close()
+	var cached header
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&cached); err != nil {
+		return header{}, fmt.Errorf("env header decode: %w", err)
+	}
+	return cached, nil
+}
+
+func writeHeader(current header) error {
+	return writeAtomically(headerPath(), func(writer io.Writer) error {
+		return gob.NewEncoder(writer).Encode(current)
+	})
+}
+
+// writeEnvfile writes the grid file, then the rest of the environment to the envfile, and the header last
+// All of them are written to temporary files that replace the previous ones once complete, so that an interrupted build never leaves a half-written cache behind,
+// the build ID of the header not matching the other files until it is written
+func writeEnvfile(env *Environment, current header) error {
+	if err := os.MkdirAll(paths.CacheDir, 0755); err != nil {
+		return fmt.Errorf("cache directory: %w", err)
+	}
+	err := writeAtomically(gridsPath(), func(writer io.Writer) error {
+		return searchgrids.WriteGrids(writer, current.Built.UnixNano(), env.grids())
+	})
+	if err != nil {
+		return fmt.Errorf("grid file write: %w", err)
+	}
+	err = writeAtomically(envfilePath(), func(writer io.Writer) error {
+		encoder := gob.NewEncoder(writer)
+		if err := encoder.Encode(current.Built.UnixNano()); err != nil {
+			return err
+		}
+		return encoder.Encode(env.withoutGrids())
+	})
+	if err != nil {
+		return fmt.Errorf("env file write: %w", err)
+	}
+	if err = writeHeader(current); err != nil {
+		return fmt.Errorf("env header write: %w", err)
+	}
+	return nil
+}
+
+func writeAtomically(path string, write func(writer io.Writer) error) error {
+	temporary, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(temporary)
+	err = write(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = temporary.Sync()
+	}
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temporary.Name())
+	}
+	return err
+}
+
+// grids lists the grids in the order of the grid file
+func (env *Environment) grids() []searchgrids.Griddable {
+	return []searchgrids.Griddable{
+		env.English, env.Kana, env.Kanji,
+		env.EnglishSuffix, env.KanaSuffix, env.KanjiSuffix,
+		env.NameEnglish, env.NameKana, env.NameKanji,
+	}
+}
+
+func (env *Environment) attachGrids(grids []*searchgrids.MappedGrid) error {
+	if len(grids) != len(env.grids()) {
+		return fmt.Errorf("grid file holds %v grids, expected %v", len(grids), len(env.grids()))
+	}
+	env.English, env.Kana, env.Kanji = grids[0].English(), grids[1].Kana(), grids[2].Kanji()
+	env.EnglishSuffix, env.KanaSuffix, env.KanjiSuffix = grids[3].English(), grids[4].Kana(), grids[5].Kanji()
+	env.NameEnglish, env.NameKana, env.NameKanji = grids[6].English(), grids[7].Kana(), grids[8].Kanji()
+	return nil
+}
+
+// withoutGrids copies the environment without its grids, which go to the grid file
+func (env Environment) withoutGrids() Environment {
+	env.English, env.Kana, env.Kanji = nil, nil, nil
+	env.EnglishSuffix, env.KanaSuffix, env.KanjiSuffix = nil, nil, nil
+	env.NameEnglish, env.NameKana, env.NameKanji = nil, nil, nil
+	return env
+}
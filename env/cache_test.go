@@ -0,0 +1,112 @@
+package env
+
+import (
+	"fmt"
+	"japp/config"
+	"japp/searchgrids"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// configureTemp points every source file into a temporary directory, JMdict being the only one that exists
+func configureTemp(t testing.TB) string {
+	dir := t.TempDir()
+	settings := config.Config{DataDir: dir, CacheDir: filepath.Join(dir, "cache")}
+	for _, setting := range config.Settings {
+		if setting.Key != "data_dir" && setting.Key != "cache_dir" {
+			*setting.Field(&settings) = filepath.Join(dir, setting.Key)
+		}
+	}
+	Configure(settings)
+	t.Cleanup(func() { Configure(config.Default()) })
+	if err := os.WriteFile(settings.JMdict, []byte("<JMdict></JMdict>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return settings.JMdict
+}
+
+func TestTouchedSourceUpdatesHeader(t *testing.T) {
+	jmdictPath := configureTemp(t)
+	current, err := newHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, fresh := current.fresh(); !fresh {
+		t.Fatal("a new header isn't fresh")
+	}
+	touched := time.Now().Add(time.Hour)
+	if err := os.Chtimes(jmdictPath, touched, touched); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, fresh := current.fresh()
+	if !fresh {
+		t.Fatal("touching JMdict without changing it made the header stale")
+	}
+	if !refreshed.touched(current) || !refreshed.Sources[0].ModTime.Equal(touched) {
+		t.Errorf("the refreshed header doesn't record the new modification time of JMdict")
+	}
+	if current.Sources[0].ModTime.Equal(touched) {
+		t.Errorf("fresh changed the header it was called on")
+	}
+	if again, _ := refreshed.fresh(); again.touched(refreshed) {
+		t.Errorf("the refreshed header still sees JMdict as touched")
+	}
+	if err := os.WriteFile(jmdictPath, []byte("<jmdict></jmdict>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, fresh := refreshed.fresh(); fresh {
+		t.Errorf("a changed JMdict of the same size doesn't make the header stale")
+	}
+}
+
+// syntheticDict builds a dictionary shaped like JMdict, with a kanji form, two readings and two senses per entry
+func syntheticDict(size int) *jmdict.Jmdict {
+	kana := []rune("あいうえおかきくけこさしすせそたちつてとなにぬねのはひふへほまみむめもやゆよらりるれろわをん")
+	var dict jmdict.Jmdict
+	for i := 0; i < size; i++ {
+		kanji := string([]rune{rune(0x4E00 + i%20000), rune(0x4E00 + (i*7)%20000)})
+		reading := string([]rune{kana[i%len(kana)], kana[(i/len(kana))%len(kana)], kana[(i*3)%len(kana)]})
+		entry := jmdict.JmdictEntry{
+			Sequence: 1000000 + i,
+			Kanji:    []jmdict.JmdictKanji{{Expression: kanji, Priorities: []string{"news1"}}},
+			Readings: []jmdict.JmdictReading{{Reading: reading}, {Reading: reading + "い"}},
+		}
+		for sense := 0; sense < 2; sense++ {
+			entry.Sense = append(entry.Sense, jmdict.JmdictSense{
+				PartsOfSpeech: []string{"noun (common) (futsuumeishi)"},
+				Glossary: []jmdict.JmdictGlossary{
+					{Content: fmt.Sprintf("meaning %v of word %v", sense, i)},
+					{Content: fmt.Sprintf("another gloss %v", i%1000)},
+				},
+			})
+		}
+		dict.Entries = append(dict.Entries, entry)
+	}
+	return &dict
+}
+
+// BenchmarkReadCache measures a start with an up to date cache, which maps the grids and decodes the rest of the environment
+// JMdict holds about 210,000 entries
+func BenchmarkReadCache(b *testing.B) {
+	configureTemp(b)
+	current, err := newHeader()
+	if err != nil {
+		b.Fatal(err)
+	}
+	env := Environment{Dict: syntheticDict(210000)}
+	env.English, env.Kana, env.Kanji = searchgrids.GenerateAlphabets(*env.Dict)
+	env.EnglishSuffix, env.KanaSuffix, env.KanjiSuffix = searchgrids.GenerateSuffixAlphabets(*env.Dict)
+	if err := writeEnvfile(&env, current); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := readCache(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,9 +4,13 @@ package env
 
 import (
 	"bufio"
-	"encoding/gob"
+	"fmt"
+	"japp/config"
+	"japp/examples"
+	"japp/kanjidic"
+	"japp/pitch"
+	"japp/radicals"
 	"japp/searchgrids"
-	"log"
 	"os"
 
 	"foosoft.net/projects/jmdict"
@@ -16,71 +20,125 @@ import (
 // These grids are basically 3D arrays of linked lists that would allow us to perform quick lookup of words in English, Hiragana/Katakana and Kanji
 
 type Environment struct {
-	Dict    *jmdict.Jmdict
-	English *searchgrids.EngAlphabet
-	Kana    *searchgrids.KanaAlphabet
-	Kanji   *searchgrids.KanjiAlphabet
+	Dict *jmdict.Jmdict
+	// The normalized kanji forms and readings of Dict, built together with it, see KanjiForm and Reading
+	Forms Forms
+	// KANJIDIC2, holding the readings, meanings and statistics of single kanji. It stays nil if kanjidic2.xml is missing
+	Kanjidic *jmdict.Kanjidic
+	// The positions of the kanji of Kanjidic by their literal, built together with it
+	KanjiIndex kanjidic.Index
+	// RADKFILE and KRADFILE, used to find kanji by their radicals. It stays nil if radkfile is missing
+	Radicals *radicals.Index
+	English  *searchgrids.EngAlphabet
+	Kana     *searchgrids.KanaAlphabet
+	Kanji    *searchgrids.KanjiAlphabet
+	// The same grids built from words written backwards, used to look up the end of a word
+	EnglishSuffix *searchgrids.EngAlphabet
+	KanaSuffix    *searchgrids.KanaAlphabet
+	KanjiSuffix   *searchgrids.KanjiAlphabet
+	// Example sentences of the Tanaka corpus linked to the WordIDs of Dict. It stays nil if examples.utf is missing
+	Examples *examples.Corpus
+	// Pitch accents by reading, from a Kanjium-style TSV. It stays nil if accents.txt is missing
+	Accents *pitch.Index
+	// JMnedict, the dictionary of proper names, with its own grids. Its entries are converted to the JMdict layout, the name types being listed in the Misc of every sense
+	// Names stays nil if JMnedict.xml is missing
+	Names       *jmdict.Jmdict
+	NameEnglish *searchgrids.EngAlphabet
+	NameKana    *searchgrids.KanaAlphabet
+	NameKanji   *searchgrids.KanjiAlphabet
+	NameForms   Forms
 	// Groups *searchgrids.Groups
 }
 
+// paths tells where the source files and the envfile are, Configure replacing the defaults of the config package
+
+var paths = config.Default()
+
+func Configure(settings config.Config) {
+	paths = settings
+}
+
+// NamesEnvironment gives the names dictionary and its grids in place of JMdict, so that names can be searched exactly like words
+
+func (env Environment) NamesEnvironment() Environment {
+	return Environment{Dict: env.Names, Forms: env.NameForms, English: env.NameEnglish, Kana: env.NameKana, Kanji: env.NameKanji}
+}
+
 // This is the first function that is called on bootup of the program - it checks for the pre-made environment encoded into a binary file
-// If the file is missing, it creates one using the functions below
+// If the file is missing, out of date or damaged, it creates one using the functions below
 // If the read is successful, we simply return the pointer to the environment to the main function
 
 func Initialize() (*Environment, error) {
-	var env *Environment
-	var err error
-	envfilename := "env/envfile"
-	if envfile, err := os.Open(envfilename); err == nil {
-		env, err = readGobENV(envfile)
-		if err != nil {
-			log.Fatal("gob env: ", err)
-		}
-		defer envfile.Close()
-	} else if os.IsNotExist(err) {
-		env, err = writeGobENV()
-		if err != nil {
-			log.Fatal("gob env write: ", err)
-		}
+	env, err := readCache()
+	if err != nil {
+		return writeGobENV()
 	}
-	return env, err
+	return env, nil
 }
 
-// If the binary environment file is present, we decode it using this function
+// Rebuild parses JMdict again and overwrites the binary environment file, even if it looks up to date
 
-func readGobENV(file *os.File) (*Environment, error) {
-	var env Environment
-	var err error
-	decoder := gob.NewDecoder(file)
-	err = decoder.Decode(&env)
-	return &env, err
+func Rebuild() (*Environment, error) {
+	return writeGobENV()
 }
 
-// If it is missing, we create an environment struct, encode it into binary, and write it to the file
+// We create an environment struct, encode it into binary, and write it to the file
 // First element of the struct is JMDict dictionary that we get by parsing the XML file using foosoft's module. These guys are our saviors!
 // Elements 2-4 are more interesting and are explained in the searchgrids package
+// The sources are fingerprinted before they are parsed, so that a file changing during the build is noticed on the next start
 
 func writeGobENV() (*Environment, error) {
 	var env Environment
-	var err error
+	current, err := newHeader()
+	if err != nil {
+		return nil, err
+	}
 	env.Dict, err = dictInit()
 	if err != nil {
-		log.Fatal()
+		return nil, err
 	}
-	env.English, env.Kana, env.Kanji = searchgrids.GenerateAlphabets(*env.Dict)
-	// env.Furigana = searchgrids.GenerateFuriganaSearchGrid(env.Dict)
-	// env.Kanji = searchgrids.GenerateKanjiSearchGrid(env.Dict)
-	envfile, err := os.Create("env/envfile")
+	env.Forms = NewForms(env.Dict)
+	env.Kanjidic, err = kanjidicInit()
 	if err != nil {
-		log.Fatal("env file write: ", err)
+		return nil, err
 	}
-	defer envfile.Close()
-	encoder := gob.NewEncoder(envfile)
-	err = encoder.Encode(env)
+	env.KanjiIndex = kanjidic.NewIndex(env.Kanjidic)
+	env.Radicals, err = radicalsInit()
 	if err != nil {
-		log.Fatal("env encode: ", err)
+		return nil, err
 	}
-	return &env, err
+	env.Examples, err = examplesInit(env.Dict)
+	if err != nil {
+		return nil, err
+	}
+	env.Accents, err = accentsInit()
+	if err != nil {
+		return nil, err
+	}
+	env.Names, err = namesInit()
+	if err != nil {
+		return nil, err
+	}
+	env.NameForms = NewForms(env.Names)
+	// Every entry of JMdict is indexed twice, once for the regular grids and once written backwards for the suffix grids
+	total := 2 * len(env.Dict.Entries)
+	if env.Names != nil {
+		total += len(env.Names.Entries)
+	}
+	progress := newIndexProgress("Building the search grids", total)
+	if env.Names != nil {
+		env.NameEnglish, env.NameKana, env.NameKanji = searchgrids.Generate(*env.Names, false, progress.add)
+	}
+	env.English, env.Kana, env.Kanji = searchgrids.Generate(*env.Dict, false, progress.add)
+	env.EnglishSuffix, env.KanaSuffix, env.KanjiSuffix = searchgrids.Generate(*env.Dict, true, progress.add)
+	// env.Furigana = searchgrids.GenerateFuriganaSearchGrid(env.Dict)
+	// env.Kanji = searchgrids.GenerateKanjiSearchGrid(env.Dict)
+	reportStage("Writing the cache")
+	if err = writeEnvfile(&env, current); err != nil {
+		return nil, err
+	}
+	reportStage("")
+	return &env, nil
 }
 
 // This function is the one that uses the foosoft parser to create a dictionary element
@@ -88,15 +146,145 @@ func writeGobENV() (*Environment, error) {
 func dictInit() (*jmdict.Jmdict, error) {
 	var dict jmdict.Jmdict
 	var err error
-	file, err := os.Open("env/JMdict_e")
+	file, err := os.Open(paths.JMdict)
 	if err != nil {
-		log.Fatal("JMdict file missing or corrupted: ", err)
+		return nil, fmt.Errorf("JMdict file missing or corrupted: %w", err)
 	}
 	defer file.Close()
+	reportStage("Reading JMdict")
 	reader := bufio.NewReader(file)
 	dict, _, err = jmdict.LoadJmdict(reader)
 	if err != nil {
-		log.Fatal("JMdict file parsing error: ", err)
+		return nil, fmt.Errorf("JMdict file parsing error: %w", err)
+	}
+	return &dict, nil
+}
+
+// KANJIDIC2 is optional, the dictionary works without it but can't show information about single kanji
+
+func kanjidicInit() (*jmdict.Kanjidic, error) {
+	file, err := os.Open(paths.Kanjidic)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("KANJIDIC2 file open: %w", err)
+	}
+	defer file.Close()
+	reportStage("Reading KANJIDIC2")
+	kanjidic, err := jmdict.LoadKanjidic(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("KANJIDIC2 file parsing error: %w", err)
+	}
+	return &kanjidic, nil
+}
+
+// The radical files are optional as well, KRADFILE only being needed to tell which radicals can still narrow a search down
+
+func radicalsInit() (*radicals.Index, error) {
+	radkfile, err := os.Open(paths.Radkfile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("RADKFILE open: %w", err)
+	}
+	defer radkfile.Close()
+	reportStage("Reading RADKFILE and KRADFILE")
+	var index radicals.Index
+	index.Radicals, err = radicals.LoadRadkfile(radkfile)
+	if err != nil {
+		return nil, fmt.Errorf("RADKFILE parsing error: %w", err)
+	}
+	kradfile, err := os.Open(paths.Kradfile)
+	if os.IsNotExist(err) {
+		return &index, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("KRADFILE open: %w", err)
+	}
+	defer kradfile.Close()
+	index.Components, err = radicals.LoadKradfile(kradfile)
+	if err != nil {
+		return nil, fmt.Errorf("KRADFILE parsing error: %w", err)
+	}
+	return &index, nil
+}
+
+// JMnedict is optional too. Its entries only differ from JMdict ones in their translations, which become senses carrying the name types
+
+func namesInit() (*jmdict.Jmdict, error) {
+	file, err := os.Open(paths.JMnedict)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("JMnedict file open: %w", err)
+	}
+	defer file.Close()
+	reportStage("Reading JMnedict")
+	names, _, err := jmdict.LoadJmnedict(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("JMnedict file parsing error: %w", err)
+	}
+	var dict jmdict.Jmdict
+	for _, name := range names.Entries {
+		dict.Entries = append(dict.Entries, nameEntry(name))
+	}
+	return &dict, nil
+}
+
+func nameEntry(name jmdict.JmnedictEntry) jmdict.JmdictEntry {
+	entry := jmdict.JmdictEntry{Sequence: name.Sequence}
+	for _, kanji := range name.Kanji {
+		entry.Kanji = append(entry.Kanji, jmdict.JmdictKanji{Expression: kanji.Expression, Information: kanji.Information, Priorities: kanji.Priorities})
+	}
+	for _, reading := range name.Readings {
+		entry.Readings = append(entry.Readings, jmdict.JmdictReading{
+			Reading:      reading.Reading,
+			Restrictions: reading.Restrictions,
+			Information:  reading.Information,
+			Priorities:   reading.Priorities,
+		})
+	}
+	for _, translation := range name.Translations {
+		sense := jmdict.JmdictSense{Misc: translation.NameTypes, References: translation.References}
+		for _, content := range translation.Translations {
+			sense.Glossary = append(sense.Glossary, jmdict.JmdictGlossary{Content: content})
+		}
+		entry.Sense = append(entry.Sense, sense)
+	}
+	return entry
+}
+
+// The example sentences are optional as well, and are linked to the entries of the dictionary once, when the environment is built
+
+func examplesInit(dict *jmdict.Jmdict) (*examples.Corpus, error) {
+	file, err := os.Open(paths.Examples)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("examples file open: %w", err)
+	}
+	defer file.Close()
+	reportStage("Reading the example sentences")
+	corpus, err := examples.Load(file, dict)
+	if err != nil {
+		return nil, fmt.Errorf("examples file parsing error: %w", err)
+	}
+	return corpus, nil
+}
+
+// The pitch accents are optional too
+
+func accentsInit() (*pitch.Index, error) {
+	file, err := os.Open(paths.Accents)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("accents file open: %w", err)
+	}
+	defer file.Close()
+	reportStage("Reading the pitch accents")
+	index, err := pitch.Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("accents file parsing error: %w", err)
 	}
-	return &dict, err
+	return index, nil
 }
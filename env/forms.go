@@ -0,0 +1,57 @@
+package env
+
+import (
+	"japp/normalize"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// Forms holds the kanji forms and readings of a dictionary in their normalized form, which is what queries are compared with
+// Nearly every entry of JMdict is written the normalized way already, so only the entries having a form that normalize.String changes are kept, by WordID
+type Forms struct {
+	Kanji    map[int][]string
+	Readings map[int][]string
+}
+
+// NewForms normalizes the dictionary once, so that searches don't have to normalize every candidate they look at
+func NewForms(dict *jmdict.Jmdict) Forms {
+	forms := Forms{Kanji: map[int][]string{}, Readings: map[int][]string{}}
+	if dict == nil {
+		return forms
+	}
+	for wordID, entry := range dict.Entries {
+		var kanji, readings []string
+		changed := false
+		for _, form := range entry.Kanji {
+			kanji = append(kanji, normalize.String(form.Expression))
+			changed = changed || kanji[len(kanji)-1] != form.Expression
+		}
+		if changed {
+			forms.Kanji[wordID] = kanji
+		}
+		changed = false
+		for _, reading := range entry.Readings {
+			readings = append(readings, normalize.String(reading.Reading))
+			changed = changed || readings[len(readings)-1] != reading.Reading
+		}
+		if changed {
+			forms.Readings[wordID] = readings
+		}
+	}
+	return forms
+}
+
+// KanjiForm and Reading give a kanji form or a reading of an entry of Dict in its normalized form
+func (env Environment) KanjiForm(wordID, index int) string {
+	if kanji, found := env.Forms.Kanji[wordID]; found {
+		return kanji[index]
+	}
+	return env.Dict.Entries[wordID].Kanji[index].Expression
+}
+
+func (env Environment) Reading(wordID, index int) string {
+	if readings, found := env.Forms.Readings[wordID]; found {
+		return readings[index]
+	}
+	return env.Dict.Entries[wordID].Readings[index].Reading
+}
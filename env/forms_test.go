@@ -0,0 +1,33 @@
+package env
+
+import (
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+func TestNewForms(t *testing.T) {
+	dict := &jmdict.Jmdict{Entries: []jmdict.JmdictEntry{
+		{Kanji: []jmdict.JmdictKanji{{Expression: "猫"}}, Readings: []jmdict.JmdictReading{{Reading: "ねこ"}}},
+		{Kanji: []jmdict.JmdictKanji{{Expression: "時時"}, {Expression: "時々"}}, Readings: []jmdict.JmdictReading{{Reading: "ときどき"}}},
+		{Readings: []jmdict.JmdictReading{{Reading: "いすゞ"}, {Reading: "いすず"}}},
+	}}
+	env := Environment{Dict: dict, Forms: NewForms(dict)}
+	if len(env.Forms.Kanji) != 1 || len(env.Forms.Readings) != 1 {
+		t.Errorf("%v kanji forms and %v readings kept, expected only the entries that normalizing changes", len(env.Forms.Kanji), len(env.Forms.Readings))
+	}
+	for _, test := range []struct {
+		form, expected string
+	}{
+		{env.KanjiForm(0, 0), "猫"},
+		{env.Reading(0, 0), "ねこ"},
+		{env.KanjiForm(1, 0), "時時"},
+		{env.KanjiForm(1, 1), "時時"},
+		{env.Reading(2, 0), "いすず"},
+		{env.Reading(2, 1), "いすず"},
+	} {
+		if test.form != test.expected {
+			t.Errorf("form %q, expected %q", test.form, test.expected)
+		}
+	}
+}
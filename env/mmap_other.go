@@ -0,0 +1,10 @@
+//go:build !unix
+
+package env
+
+import "os"
+
+// mapFile reads the whole file on systems without mmap, the grids are still only decoded when a search needs them
+func mapFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
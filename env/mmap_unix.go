@@ -0,0 +1,26 @@
+//go:build unix
+
+package env
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mapFile maps a file read-only into memory, the pages being read from the disk only when they are accessed
+// The mapping stays valid after the file is replaced, since the cache files are renamed over rather than rewritten
+func mapFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	} else if info.Size() == 0 {
+		return nil, errors.New("empty file")
+	}
+	return syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
@@ -0,0 +1,41 @@
+package env
+
+import "sync"
+
+// BuildProgress, when set, is told how far a build of the environment got, so that a long first start shows more than a waiting message
+// It gets the stage being run, and while the grids are built the number of entries indexed out of the total. An empty stage means that the build is over
+var BuildProgress func(stage string, done, total int)
+
+func reportStage(stage string) {
+	if BuildProgress != nil {
+		BuildProgress(stage, 0, 0)
+	}
+}
+
+// indexProgress adds up the entries indexed by the goroutines building the grids and reports every new percent
+type indexProgress struct {
+	mutex    sync.Mutex
+	stage    string
+	done     int
+	total    int
+	reported int
+}
+
+func newIndexProgress(stage string, total int) *indexProgress {
+	progress := indexProgress{stage: stage, total: total, reported: -1}
+	progress.add(0)
+	return &progress
+}
+
+func (progress *indexProgress) add(entries int) {
+	if BuildProgress == nil || progress.total == 0 {
+		return
+	}
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	progress.done += entries
+	if percent := progress.done * 100 / progress.total; percent != progress.reported {
+		progress.reported = percent
+		BuildProgress(progress.stage, progress.done, progress.total)
+	}
+}
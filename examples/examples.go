@@ -0,0 +1,172 @@
+package examples
+
+// This package reads the Tanaka corpus (the Tatoeba sentences in the examples.utf file distributed by the EDRDG) and links its sentences to JMdict entries
+// Every sentence comes as two lines:
+//	A: 彼は忙しい生活の中で家族と会うことがない。	He doesn't see his family in his busy life.#ID=303645_100000
+//	B: 彼(かれ)[01] は 忙しい 生活 の 中 で 家族 と 会う 事{こと} が 無い{ない}
+// The B line lists the dictionary form of every word, optionally followed by its reading in (), a sense number in [], the form used in the sentence in {}
+// and a ~ when the sentence is a good example of the word. The dictionary form and reading are what we use to find the JMdict entries
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// MissingMessage explains what to do when the environment was built without the corpus
+const MissingMessage = "The example sentences are not loaded, download examples.utf into the data directory, it is loaded on the next start"
+
+type Sentence struct {
+	Japanese string
+	English  string
+}
+
+// Corpus holds the sentences and, for every WordID of JMdict, the indexes of the sentences using the word, the good examples first
+type Corpus struct {
+	Sentences []Sentence
+	Words     map[int][]int
+}
+
+// Load reads the corpus and links its words to the entries of the dictionary
+func Load(reader io.Reader, dict *jmdict.Jmdict) (*Corpus, error) {
+	corpus := Corpus{Words: make(map[int][]int)}
+	index := newWordIndex(dict)
+	good := make(map[int][]int)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "A: ") {
+			japanese, english, _ := strings.Cut(strings.TrimPrefix(line, "A: "), "\t")
+			english, _, _ = strings.Cut(english, "#ID=")
+			corpus.Sentences = append(corpus.Sentences, Sentence{Japanese: japanese, English: english})
+		} else if strings.HasPrefix(line, "B: ") && len(corpus.Sentences) != 0 {
+			sentence := len(corpus.Sentences) - 1
+			linked := make(map[int]bool)
+			for _, token := range strings.Fields(strings.TrimPrefix(line, "B: ")) {
+				word, reading, example := parseToken(token)
+				for _, wordID := range index.find(word, reading) {
+					if linked[wordID] {
+						continue
+					}
+					linked[wordID] = true
+					if example {
+						good[wordID] = append(good[wordID], sentence)
+					} else {
+						corpus.Words[wordID] = append(corpus.Words[wordID], sentence)
+					}
+				}
+			}
+		}
+	}
+	for wordID, sentences := range good {
+		corpus.Words[wordID] = append(sentences, corpus.Words[wordID]...)
+	}
+	return &corpus, scanner.Err()
+}
+
+// For returns up to limit sentences using the word, all of them if the limit is 0
+func (corpus *Corpus) For(wordID int, limit int) []Sentence {
+	if corpus == nil {
+		return nil
+	}
+	var sentences []Sentence
+	for _, index := range corpus.Words[wordID] {
+		if limit > 0 && len(sentences) == limit {
+			break
+		}
+		sentences = append(sentences, corpus.Sentences[index])
+	}
+	return sentences
+}
+
+// parseToken splits a word of a B line like "事(こと)[02]{こと}~" into its dictionary form, its reading and whether it is a good example
+func parseToken(token string) (word, reading string, example bool) {
+	if strings.HasSuffix(token, "~") {
+		example = true
+		token = strings.TrimSuffix(token, "~")
+	}
+	if start := strings.Index(token, "{"); start != -1 {
+		token = token[:start]
+	}
+	if start := strings.Index(token, "["); start != -1 {
+		token = token[:start]
+	}
+	if start := strings.Index(token, "("); start != -1 && strings.HasSuffix(token, ")") {
+		reading = token[start+1 : len(token)-1]
+		token = token[:start]
+	}
+	return token, reading, example
+}
+
+// wordIndex finds entries by kanji form or reading
+type wordIndex struct {
+	kanji    map[string][]int
+	readings map[string][]int
+	dict     *jmdict.Jmdict
+}
+
+func newWordIndex(dict *jmdict.Jmdict) wordIndex {
+	index := wordIndex{kanji: make(map[string][]int), readings: make(map[string][]int), dict: dict}
+	for wordID, entry := range dict.Entries {
+		for _, kanji := range entry.Kanji {
+			index.kanji[kanji.Expression] = append(index.kanji[kanji.Expression], wordID)
+		}
+		for _, reading := range entry.Readings {
+			index.readings[reading.Reading] = append(index.readings[reading.Reading], wordID)
+		}
+	}
+	return index
+}
+
+// find returns the entries written as the word, keeping the ones with the given reading if there is one
+// Words written in kana in the corpus are looked up among the readings, see kanaWords
+func (index wordIndex) find(word, reading string) []int {
+	candidates, found := index.kanji[word]
+	if !found {
+		return index.kanaWords(index.readings[word])
+	}
+	if reading == "" {
+		return candidates
+	}
+	var matching []int
+	for _, wordID := range candidates {
+		for _, entry_reading := range index.dict.Entries[wordID].Readings {
+			if entry_reading.Reading == reading {
+				matching = append(matching, wordID)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// kanaWords keeps the entries that are written in kana, having no kanji form or being marked as usually written in kana
+// A word like の is the reading of many kanji words (野, 乃) that the corpus would have written with their kanji,
+// so those are only linked when no entry is written in kana, and then only the first of them
+func (index wordIndex) kanaWords(candidates []int) []int {
+	var kana_words []int
+	for _, wordID := range candidates {
+		if entry := index.dict.Entries[wordID]; len(entry.Kanji) == 0 || usuallyKana(entry) {
+			kana_words = append(kana_words, wordID)
+		}
+	}
+	if len(kana_words) == 0 && len(candidates) != 0 {
+		return candidates[:1]
+	}
+	return kana_words
+}
+
+// usuallyKana tells whether a sense of the entry is marked with the uk tag, which JMdict expands to "word usually written using kana alone"
+func usuallyKana(entry jmdict.JmdictEntry) bool {
+	for _, sense := range entry.Sense {
+		for _, misc := range sense.Misc {
+			if misc == "uk" || strings.Contains(misc, "usually written using kana") {
+				return true
+			}
+		}
+	}
+	return false
+}
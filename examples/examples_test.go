@@ -0,0 +1,113 @@
+package examples
+
+import (
+	"strings"
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+func TestParseToken(t *testing.T) {
+	for _, test := range []struct {
+		token, word, reading string
+		example              bool
+	}{
+		{"の", "の", "", false},
+		{"彼(かれ)", "彼", "かれ", false},
+		{"彼(かれ)[01]", "彼", "かれ", false},
+		{"事{こと}", "事", "", false},
+		{"事(こと)[02]{こと}~", "事", "こと", true},
+		{"会う{会わ}~", "会う", "", true},
+		{"生活~", "生活", "", true},
+		{"為る(する){し}", "為る", "する", false},
+	} {
+		word, reading, example := parseToken(test.token)
+		if word != test.word || reading != test.reading || example != test.example {
+			t.Errorf("parseToken(%q) = %q, %q, %v, expected %q, %q, %v", test.token, word, reading, example, test.word, test.reading, test.example)
+		}
+	}
+}
+
+func entry(kanji, reading string, misc ...string) jmdict.JmdictEntry {
+	built := jmdict.JmdictEntry{Readings: []jmdict.JmdictReading{{Reading: reading}}, Sense: []jmdict.JmdictSense{{Misc: misc}}}
+	if kanji != "" {
+		built.Kanji = []jmdict.JmdictKanji{{Expression: kanji}}
+	}
+	return built
+}
+
+const corpus = `A: 彼は野の花が好きだ。	He likes wild flowers.#ID=1_1
+B: 彼(かれ)[01] は 野(の){野} の 花 が 好き~ だ
+A: 事がないの。	It never happens.#ID=2_2
+B: 事(こと){事} が 無い{ない} の
+A: かれは来ない。	He doesn't come.#ID=3_3
+B: かれ は 来る(くる){来}~ ない
+`
+
+func TestLoad(t *testing.T) {
+	dict := &jmdict.Jmdict{Entries: []jmdict.JmdictEntry{
+		entry("彼", "かれ"),  // 0
+		entry("枯れ", "かれ"), // 1
+		entry("野", "の"),   // 2
+		entry("", "の"),    // 3, the particle
+		entry("花", "はな"),  // 4
+		entry("好き", "すき"), // 5
+		entry("事", "こと"),  // 6
+		entry("無い", "ない", "word usually written using kana alone"), // 7
+		entry("亡い", "ない"),  // 8
+		entry("来る", "くる"),  // 9
+		entry("来る", "きたる"), // 10
+	}}
+	loaded, err := Load(strings.NewReader(corpus), dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Sentences) != 3 || loaded.Sentences[0] != (Sentence{Japanese: "彼は野の花が好きだ。", English: "He likes wild flowers."}) {
+		t.Fatalf("Load read the sentences %v", loaded.Sentences)
+	}
+	for _, test := range []struct {
+		wordID    int
+		sentences []int
+	}{
+		{0, []int{0, 2}}, // かれ has no entry written in kana, so only the first of its kanji words gets it
+		{1, nil},
+		{2, []int{0}},
+		{3, []int{0, 1}}, // の goes to the particle and not to 野
+		{5, []int{0}},    // marked with ~
+		{7, []int{1, 2}}, // 無い by its kanji form, then ない as a word usually written in kana
+		{8, nil},
+		{9, []int{2}},
+		{10, nil}, // 来る is read くる in the sentence
+	} {
+		if sentences := loaded.Words[test.wordID]; !sameInts(sentences, test.sentences) {
+			t.Errorf("the entry %v is used in the sentences %v, expected %v", test.wordID, sentences, test.sentences)
+		}
+	}
+}
+
+// The good examples, marked with ~, come first
+func TestGoodExamplesFirst(t *testing.T) {
+	dict := &jmdict.Jmdict{Entries: []jmdict.JmdictEntry{entry("猫", "ねこ")}}
+	loaded, err := Load(strings.NewReader("A: 猫だ。\tA cat.#ID=1\nB: 猫\nA: 猫がいる。\tThere is a cat.#ID=2\nB: 猫~ が 居る{いる}\n"), dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sentences := loaded.For(0, 0); len(sentences) != 2 || sentences[0].English != "There is a cat." {
+		t.Errorf("For gives %v, expected the good example first", sentences)
+	}
+	if sentences := loaded.For(0, 1); len(sentences) != 1 {
+		t.Errorf("For with a limit of 1 gives %v", sentences)
+	}
+}
+
+func sameInts(first, second []int) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
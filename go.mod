@@ -2,9 +2,10 @@ module japp
 
 go 1.19
 
+require github.com/inancgumus/screen v0.0.0-20190314163918-06e984b86ed3
+
 require (
 	foosoft.net/projects/jmdict v0.0.0-20220714211640-cc9bc30b68a3 // indirect
-	github.com/inancgumus/screen v0.0.0-20190314163918-06e984b86ed3 // indirect
 	golang.org/x/crypto v0.6.0 // indirect
 	golang.org/x/mobile v0.0.0-20221110043201-43a038452099 // indirect
 	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4 // indirect
@@ -15,3 +15,55 @@ func IsHiragana(character rune) bool {
 		return false
 	}
 }
+
+// HiraganaToKatakana shifts every hiragana character of the string into the katakana block, leaving everything else untouched
+func HiraganaToKatakana(word string) string {
+	converted := []rune(word)
+	for i, character := range converted {
+		if IsHiragana(character) {
+			converted[i] = character + 96
+		}
+	}
+	return string(converted)
+}
+
+// KatakanaToHiragana shifts every katakana character from ァ to ヶ into the hiragana block, leaving everything else untouched
+// It only changes the script, one character for another: ヴ becomes ゔ, ヵ and ヶ become ゕ and ゖ, and the long vowel mark ー, which both scripts use, stays
+func KatakanaToHiragana(word string) string {
+	converted := []rune(word)
+	for i, character := range converted {
+		if character >= 'ァ' && character <= 'ヶ' {
+			converted[i] = character - 96
+		}
+	}
+	return string(converted)
+}
+
+// ExpandLongVowels writes every long vowel mark ー after a hiragana out with the kana hiragana uses for its vowel, the same way FromRomaji spells long vowels,
+// so that こーひー becomes こうひい. Katakana have to go through KatakanaToHiragana first
+// Different words can become the same string (ぼーと and ぼうと both become ぼうと), so it is only meant for comparing two texts that both went through it
+func ExpandLongVowels(word string) string {
+	expanded := []rune(word)
+	for i, character := range expanded {
+		if character != 'ー' || i == 0 {
+			continue
+		}
+		if vowel, found := kanaVowels[expanded[i-1]]; found {
+			expanded[i] = []rune(longVowelKana[vowel])[0]
+		}
+	}
+	return string(expanded)
+}
+
+// kanaVowels gives the vowel every hiragana ends with, taken from the romaji table
+var kanaVowels = vowelsOf(syllables)
+
+func vowelsOf(table map[string]string) map[rune]byte {
+	vowels := map[rune]byte{'ぁ': 'a', 'ぃ': 'i', 'ぅ': 'u', 'ぇ': 'e', 'ぉ': 'o', 'ゃ': 'a', 'ゅ': 'u', 'ょ': 'o', 'ゎ': 'a'} // Small kana aren't syllables of their own
+	for romaji, hiragana := range table {
+		if characters := []rune(hiragana); len(characters) == 1 {
+			vowels[characters[0]] = romaji[len(romaji)-1]
+		}
+	}
+	return vowels
+}
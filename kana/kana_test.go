@@ -0,0 +1,48 @@
+package kana
+
+import "testing"
+
+func TestKatakanaToHiragana(t *testing.T) {
+	for _, test := range []struct{ katakana, hiragana string }{
+		{"ネコ", "ねこ"},
+		{"ヴァイオリン", "ゔぁいおりん"},
+		{"ヵヶ", "ゕゖ"},
+		{"一ヶ月", "一ゖ月"},
+		{"コーヒー", "こーひー"},
+		{"ケーキ", "けーき"},
+		{"メール", "めーる"},
+		{"ー", "ー"},
+		{"ねこ", "ねこ"},
+		{"ABC", "ABC"},
+	} {
+		if hiragana := KatakanaToHiragana(test.katakana); hiragana != test.hiragana {
+			t.Errorf("KatakanaToHiragana(%q) = %q, expected %q", test.katakana, hiragana, test.hiragana)
+		}
+	}
+	// The shift goes both ways for every katakana that has a hiragana counterpart
+	for character := 'ァ'; character <= 'ヵ'; character++ {
+		if back := HiraganaToKatakana(KatakanaToHiragana(string(character))); back != string(character) {
+			t.Errorf("%c becomes %v after a round trip", character, back)
+		}
+	}
+}
+
+func TestExpandLongVowels(t *testing.T) {
+	for _, test := range []struct{ word, expanded string }{
+		{"こーひー", "こうひい"},
+		{"こうひい", "こうひい"},
+		{"けーき", "けえき"},
+		{"めーる", "めえる"},
+		{"らーめん", "らあめん"},
+		{"すーぷ", "すうぷ"},
+		{"きょー", "きょう"},
+		{"ゔぁー", "ゔぁあ"},
+		{"ー", "ー"},
+		{"コーヒー", "コーヒー"}, // Katakana are left to KatakanaToHiragana
+		{"aー", "aー"},
+	} {
+		if expanded := ExpandLongVowels(test.word); expanded != test.expanded {
+			t.Errorf("ExpandLongVowels(%q) = %q, expected %q", test.word, expanded, test.expanded)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package kana
+
+import (
+	"strings"
+	"unicode"
+)
+
+// This file contains the romaji to kana transliteration used for searching Japanese words from a keyboard without an IME
+// It accepts Hepburn, Kunrei-shiki and Nihon-shiki spellings alike, since every syllable of those systems is present in the table below
+
+var syllables = map[string]string{
+	"a": "あ", "i": "い", "u": "う", "e": "え", "o": "お",
+	"ka": "か", "ki": "き", "ku": "く", "ke": "け", "ko": "こ",
+	"kya": "きゃ", "kyu": "きゅ", "kyo": "きょ", "kwa": "くゎ",
+	"ga": "が", "gi": "ぎ", "gu": "ぐ", "ge": "げ", "go": "ご",
+	"gya": "ぎゃ", "gyu": "ぎゅ", "gyo": "ぎょ", "gwa": "ぐゎ",
+	"sa": "さ", "si": "し", "shi": "し", "su": "す", "se": "せ", "so": "そ",
+	"sya": "しゃ", "syu": "しゅ", "syo": "しょ", "sha": "しゃ", "shu": "しゅ", "sho": "しょ", "she": "しぇ",
+	"za": "ざ", "zi": "じ", "ji": "じ", "zu": "ず", "ze": "ぜ", "zo": "ぞ",
+	"zya": "じゃ", "zyu": "じゅ", "zyo": "じょ", "ja": "じゃ", "ju": "じゅ", "jo": "じょ", "je": "じぇ",
+	"jya": "じゃ", "jyu": "じゅ", "jyo": "じょ",
+	"ta": "た", "ti": "ち", "chi": "ち", "tu": "つ", "tsu": "つ", "te": "て", "to": "と",
+	"tya": "ちゃ", "tyu": "ちゅ", "tyo": "ちょ", "cha": "ちゃ", "chu": "ちゅ", "cho": "ちょ", "che": "ちぇ",
+	"cya": "ちゃ", "cyu": "ちゅ", "cyo": "ちょ", "tsa": "つぁ",
+	"da": "だ", "di": "ぢ", "du": "づ", "de": "で", "do": "ど",
+	"dya": "ぢゃ", "dyu": "ぢゅ", "dyo": "ぢょ",
+	"na": "な", "ni": "に", "nu": "ぬ", "ne": "ね", "no": "の",
+	"nya": "にゃ", "nyu": "にゅ", "nyo": "にょ",
+	"ha": "は", "hi": "ひ", "hu": "ふ", "fu": "ふ", "he": "へ", "ho": "ほ",
+	"hya": "ひゃ", "hyu": "ひゅ", "hyo": "ひょ", "fa": "ふぁ", "fi": "ふぃ", "fe": "ふぇ", "fo": "ふぉ",
+	"ba": "ば", "bi": "び", "bu": "ぶ", "be": "べ", "bo": "ぼ",
+	"bya": "びゃ", "byu": "びゅ", "byo": "びょ",
+	"pa": "ぱ", "pi": "ぴ", "pu": "ぷ", "pe": "ぺ", "po": "ぽ",
+	"pya": "ぴゃ", "pyu": "ぴゅ", "pyo": "ぴょ",
+	"ma": "ま", "mi": "み", "mu": "む", "me": "め", "mo": "も",
+	"mya": "みゃ", "myu": "みゅ", "myo": "みょ",
+	"ya": "や", "yu": "ゆ", "yo": "よ",
+	"ra": "ら", "ri": "り", "ru": "る", "re": "れ", "ro": "ろ",
+	"rya": "りゃ", "ryu": "りゅ", "ryo": "りょ",
+	"wa": "わ", "wi": "うぃ", "we": "うぇ", "wo": "を",
+	"va": "ゔぁ", "vi": "ゔぃ", "vu": "ゔ", "ve": "ゔぇ", "vo": "ゔぉ",
+}
+
+// Vowels carrying a macron (Hepburn) or a circumflex (Kunrei/Nihon-shiki) are long vowels
+var longVowels = map[rune]rune{
+	'ā': 'a', 'ī': 'i', 'ū': 'u', 'ē': 'e', 'ō': 'o',
+	'â': 'a', 'î': 'i', 'û': 'u', 'ê': 'e', 'ô': 'o',
+}
+
+// In hiragana a long vowel is written out with the kana below, while katakana simply uses the ー mark
+// ē is ええ in both Hepburn and Kunrei-shiki (onēsan, お姉さん), the えい of 先生 being spelled ei, while ō stands for the much more common おう
+var longVowelKana = map[byte]string{
+	'a': "あ", 'i': "い", 'u': "う", 'e': "え", 'o': "う",
+}
+
+// Markers left by normalizeRomaji in place of long vowels
+const (
+	macron   = '^'
+	longMark = '-'
+)
+
+// FromRomaji transliterates a romaji string into its hiragana and katakana spellings
+// The last return value is false if the input contains anything that is not valid romaji, in which case the query should not be treated as Japanese
+func FromRomaji(input string) (string, string, bool) {
+	romaji := normalizeRomaji(input)
+	if romaji == "" {
+		return "", "", false
+	}
+	var hiragana, katakana strings.Builder
+	emit := func(h string) {
+		hiragana.WriteString(h)
+		katakana.WriteString(HiraganaToKatakana(h))
+	}
+	var vowel byte
+	for i := 0; i < len(romaji); {
+		char := romaji[i]
+		switch {
+		case char == ' ':
+			emit(" ")
+			vowel = 0
+			i++
+			continue
+		case char == macron || char == longMark:
+			if vowel == 0 {
+				return "", "", false
+			}
+			if char == macron {
+				hiragana.WriteString(longVowelKana[vowel])
+			} else {
+				hiragana.WriteRune('ー')
+			}
+			katakana.WriteRune('ー')
+			i++
+			continue
+		case char == '\'':
+			i++
+			continue
+		case char == 'n' && isSyllabicN(romaji, i):
+			emit("ん")
+			vowel = 0
+			if i+1 < len(romaji) && (romaji[i+1] == '\'' || (romaji[i+1] == 'n' && !startsSyllable(romaji, i+1))) {
+				i++
+			}
+			i++
+			continue
+		case char == 'm' && i+1 < len(romaji) && strings.IndexByte("bmp", romaji[i+1]) >= 0: // shimbun, sempai
+			emit("ん")
+			vowel = 0
+			i++
+			continue
+		case isGeminate(romaji, i):
+			emit("っ")
+			vowel = 0
+			i++
+			continue
+		}
+		matched := false
+		for length := 3; length > 0; length-- {
+			if i+length > len(romaji) {
+				continue
+			}
+			if kana, ok := syllables[romaji[i:i+length]]; ok {
+				emit(kana)
+				vowel = romaji[i+length-1]
+				i += length
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", "", false
+		}
+	}
+	return hiragana.String(), katakana.String(), true
+}
+
+// IsRomaji reports whether the whole string can be read as romaji
+func IsRomaji(input string) bool {
+	_, _, ok := FromRomaji(input)
+	return ok
+}
+
+// normalizeRomaji lowercases the input and rewrites every long vowel as the plain vowel followed by '^', keeping the hyphens typed by the user as '-' since they always become ー
+func normalizeRomaji(input string) string {
+	var builder strings.Builder
+	for _, char := range strings.TrimSpace(input) {
+		char = unicode.ToLower(char)
+		if vowel, ok := longVowels[char]; ok {
+			builder.WriteRune(vowel)
+			builder.WriteByte(macron)
+		} else if char == '-' || char == 'ー' {
+			builder.WriteByte(longMark)
+		} else if (char >= 'a' && char <= 'z') || char == '\'' || char == ' ' {
+			builder.WriteRune(char)
+		} else {
+			return ""
+		}
+	}
+	return builder.String()
+}
+
+// An 'n' is read as ん when it is not the start of a syllable like "na" or "nya", i.e. at the end of a word, before a consonant,
+// before an apostrophe (kan'i) or when doubled in front of something that isn't a vowel (sannen, honn)
+func isSyllabicN(romaji string, i int) bool {
+	if i+1 >= len(romaji) {
+		return true
+	}
+	next := romaji[i+1]
+	switch {
+	case next == '\'':
+		return true
+	case next == 'n':
+		return true
+	case isVowel(next) || next == 'y':
+		return false
+	}
+	return true
+}
+
+// startsSyllable reports whether an 'n' at position i opens a syllable such as "ni" or "nyo"
+func startsSyllable(romaji string, i int) bool {
+	return i+1 < len(romaji) && (isVowel(romaji[i+1]) || romaji[i+1] == 'y')
+}
+
+// A doubled consonant (kitte, zasshi) or the Hepburn "tch" (matcha) stands for the small っ
+func isGeminate(romaji string, i int) bool {
+	if i+1 >= len(romaji) {
+		return false
+	}
+	char := romaji[i]
+	if isVowel(char) || char == 'n' || char < 'a' || char > 'z' {
+		return false
+	}
+	return romaji[i+1] == char || (char == 't' && strings.HasPrefix(romaji[i+1:], "ch"))
+}
+
+func isVowel(char byte) bool {
+	return strings.IndexByte("aiueo", char) >= 0
+}
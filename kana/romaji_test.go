@@ -0,0 +1,51 @@
+package kana
+
+import "testing"
+
+func TestFromRomaji(t *testing.T) {
+	tests := []struct {
+		input    string
+		hiragana string
+		katakana string
+	}{
+		{"taberu", "たべる", "タベル"},
+		{"kitte", "きって", "キッテ"},
+		{"zasshi", "ざっし", "ザッシ"},
+		{"matcha", "まっちゃ", "マッチャ"},
+		{"kan'i", "かんい", "カンイ"},
+		{"kanji", "かんじ", "カンジ"},
+		{"hon", "ほん", "ホン"},
+		{"sannen", "さんねん", "サンネン"},
+		{"honn", "ほん", "ホン"},
+		{"kinyuu", "きにゅう", "キニュウ"},
+		{"kin'yuu", "きんゆう", "キンユウ"},
+		{"konnichiwa", "こんにちわ", "コンニチワ"},
+		{"shimbun", "しんぶん", "シンブン"},
+		{"tōkyō", "とうきょう", "トーキョー"},
+		{"kôhî", "こうひい", "コーヒー"},
+		{"onēsan", "おねえさん", "オネーサン"},
+		{"onêsan", "おねえさん", "オネーサン"},
+		{"sensei", "せんせい", "センセイ"},
+		{"ko-hi-", "こーひー", "コーヒー"},
+		{"sushi", "すし", "スシ"},
+		{"susi", "すし", "スシ"},
+		{"tizu", "ちず", "チズ"},
+		{"wiki", "うぃき", "ウィキ"},
+		{"weburogu", "うぇぶろぐ", "ウェブログ"},
+		{"Neko", "ねこ", "ネコ"},
+	}
+	for _, test := range tests {
+		hiragana, katakana, ok := FromRomaji(test.input)
+		if !ok || hiragana != test.hiragana || katakana != test.katakana {
+			t.Errorf("FromRomaji(%q) = %q, %q, %v, expected %q, %q", test.input, hiragana, katakana, ok, test.hiragana, test.katakana)
+		}
+	}
+}
+
+func TestFromRomajiRejects(t *testing.T) {
+	for _, input := range []string{"", "water", "xyz", "-ka", "猫", "ka1"} {
+		if hiragana, katakana, ok := FromRomaji(input); ok {
+			t.Errorf("FromRomaji(%q) = %q, %q, expected it to be rejected", input, hiragana, katakana)
+		}
+	}
+}
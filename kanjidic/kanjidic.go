@@ -0,0 +1,112 @@
+package kanjidic
+
+// This package turns the characters of KANJIDIC2 into the information shown to learners looking up a single kanji:
+// its readings, meanings, stroke count, school grade, JLPT level and how frequently it is used
+
+import (
+	"japp/searchgrids"
+	"strconv"
+	"unicode/utf8"
+
+	"foosoft.net/projects/jmdict"
+)
+
+type Info struct {
+	Literal     string
+	OnReadings  []string // Chinese readings, written in katakana
+	KunReadings []string // Japanese readings, in hiragana with a '.' before the okurigana
+	Nanori      []string // Readings only used in names
+	Meanings    []string // English meanings
+	StrokeCount int
+	Grade       int // 1-6 for the kanji taught in elementary school, 8 for the rest of the jouyou kanji, 9-10 for jinmeiyou kanji, 0 if none
+	JLPT        int // Level of the former four-level JLPT, 0 if the kanji wasn't part of it
+	Frequency   int // Rank among the 2500 kanji most used in newspapers, 0 if it isn't one of them
+}
+
+// MissingMessage explains what to do when the environment was built without KANJIDIC2
+const MissingMessage = "KANJIDIC2 is not loaded, download kanjidic2.xml into the data directory, it is loaded on the next start"
+
+// Index gives the position of every kanji in the characters of the dictionary, so that a lookup doesn't go through all of them
+type Index map[string]int
+
+func NewIndex(dict *jmdict.Kanjidic) Index {
+	index := make(Index)
+	if dict == nil {
+		return index
+	}
+	for i, character := range dict.Characters {
+		index[character.Literal] = i
+	}
+	return index
+}
+
+// Lookup finds a single kanji in the dictionary through its index
+func Lookup(dict *jmdict.Kanjidic, index Index, literal string) (Info, bool) {
+	if dict == nil {
+		return Info{}, false
+	}
+	i, found := index[literal]
+	if !found || i >= len(dict.Characters) {
+		return Info{}, false
+	}
+	return NewInfo(dict.Characters[i]), true
+}
+
+// StrokeCounts maps every kanji of the dictionary to its stroke count
+func StrokeCounts(dict *jmdict.Kanjidic) map[string]int {
+	counts := make(map[string]int)
+	if dict == nil {
+		return counts
+	}
+	for _, character := range dict.Characters {
+		if len(character.Misc.StrokeCounts) != 0 {
+			counts[character.Literal] = number(&character.Misc.StrokeCounts[0])
+		}
+	}
+	return counts
+}
+
+// IsSingleKanji tells whether the query is exactly one character that could have a KANJIDIC2 entry
+func IsSingleKanji(query string) bool {
+	character, size := utf8.DecodeRuneInString(query)
+	return size != 0 && size == len(query) && searchgrids.IsKanji(character)
+}
+
+func NewInfo(character jmdict.KanjidicCharacter) Info {
+	info := Info{Literal: character.Literal}
+	if len(character.Misc.StrokeCounts) != 0 {
+		info.StrokeCount = number(&character.Misc.StrokeCounts[0]) // The first count is the accepted one, the others are common miscounts
+	}
+	info.Grade = number(character.Misc.Grade)
+	info.JLPT = number(character.Misc.JlptLevel)
+	info.Frequency = number(character.Misc.Frequency)
+	if character.ReadingMeaning == nil {
+		return info
+	}
+	for _, reading := range character.ReadingMeaning.Readings {
+		switch reading.Type {
+		case "ja_on":
+			info.OnReadings = append(info.OnReadings, reading.Value)
+		case "ja_kun":
+			info.KunReadings = append(info.KunReadings, reading.Value)
+		}
+	}
+	for _, meaning := range character.ReadingMeaning.Meanings {
+		if meaning.Language == nil || *meaning.Language == "en" {
+			info.Meanings = append(info.Meanings, meaning.Meaning)
+		}
+	}
+	info.Nanori = character.ReadingMeaning.Nanori
+	return info
+}
+
+func number(value *string) int {
+	if value == nil {
+		return 0
+	}
+	parsed, err := strconv.Atoi(*value)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
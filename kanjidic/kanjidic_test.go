@@ -0,0 +1,23 @@
+package kanjidic
+
+import (
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+func TestLookup(t *testing.T) {
+	dict := &jmdict.Kanjidic{Characters: []jmdict.KanjidicCharacter{{Literal: "水"}, {Literal: "猫"}, {Literal: "𠮟"}}}
+	index := NewIndex(dict)
+	for _, literal := range []string{"水", "猫", "𠮟"} {
+		if info, found := Lookup(dict, index, literal); !found || info.Literal != literal {
+			t.Errorf("Lookup(%q) = %v, %v", literal, info, found)
+		}
+	}
+	if _, found := Lookup(dict, index, "犬"); found {
+		t.Errorf("Lookup found 犬, which is not in the dictionary")
+	}
+	if _, found := Lookup(nil, nil, "水"); found {
+		t.Errorf("Lookup found 水 without a dictionary")
+	}
+}
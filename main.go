@@ -3,23 +3,41 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"japp/cli"
 	"japp/cmdoutput"
 	"japp/env"
+	"japp/examples"
+	"japp/kanjidic"
+	"japp/radicals"
 	"japp/wordsearch"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/inancgumus/screen"
 )
 
 func main() {
+	args, err := cli.Configure(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "japp: %v\n", err)
+		os.Exit(cli.ExitError)
+	}
+	if len(args) != 0 {
+		os.Exit(cli.Run(args))
+	}
 	screen.Clear()
 	screen.MoveTopLeft()
 	fmt.Println("Initializing, please wait a moment...")
+	env.BuildProgress = cmdoutput.ProgressPrinter(os.Stdout)
 	env, err := env.Initialize()
 	screen.Clear()
 	screen.MoveTopLeft()
-	if err == nil {
+	if err != nil {
+		fmt.Println("Could not initialize the environment:", err)
+		time.Sleep(time.Second * 2)
+	} else {
 		var query string
 		scanner := bufio.NewScanner(os.Stdin)
 		query_channel := make(chan string)
@@ -27,6 +45,7 @@ func main() {
 			for {
 				time.Sleep(time.Millisecond * 200)
 				fmt.Println("Write the word you would like to find or just press Enter to exit the program")
+				fmt.Println("(:next and :prev page through the results, :limit <n> sets how many are shown, :format <name> changes the output, :kanji <kanji> describes a kanji, :radicals <radicals> finds kanji by their parts, :examples <word> shows sentences using a word, :fold on lets katakana find hiragana and back)")
 				scanner.Scan()
 				query = scanner.Text()
 				if query == "" {
@@ -37,12 +56,11 @@ func main() {
 				}
 			}
 		}()
-		for query_string := range query_channel {
-			result := wordsearch.SearchQuery(*env, query_string)
+		prompt := session{table: env, formatter: cmdoutput.TextFormatter{}, limit: wordsearch.DefaultLimit}
+		for input := range query_channel {
 			screen.Clear()
 			screen.MoveTopLeft()
-			fmt.Printf("You searched for '%v'\n\n", query_string)
-			cmdoutput.PrintResults(*env, result, query_string)
+			prompt.handle(input)
 		}
 	}
 	screen.Clear()
@@ -77,3 +95,171 @@ func main() {
 	// fmt.Println("Max gloss: ", maxGloss)
 	// fmt.Println("Max words: ", maxWords)
 }
+
+// session keeps what the prompt needs between two inputs: the chosen output and the results of the last search, so that the user can page through them
+type session struct {
+	table     *env.Environment
+	formatter cmdoutput.Formatter
+	query     string
+	results   wordsearch.ResultEntries
+	offset    int
+	limit     int
+	picks     []string // Kanji found by the last radical search, chosen with :pick
+	fold      bool     // Set with ":fold on", kana queries then match readings in either script
+}
+
+func (prompt *session) handle(input string) {
+	if input == ":next" {
+		if prompt.offset+prompt.limit < len(prompt.results) {
+			prompt.offset += prompt.limit
+		}
+		prompt.show()
+	} else if input == ":prev" {
+		prompt.offset -= prompt.limit
+		if prompt.offset < 0 {
+			prompt.offset = 0
+		}
+		prompt.show()
+	} else if value, found := cutPrefix(input, ":limit "); found {
+		if limit, err := strconv.Atoi(value); err != nil || limit < 1 {
+			fmt.Printf("The limit has to be a positive number\n\n")
+		} else {
+			prompt.limit = limit
+			prompt.offset -= prompt.offset % limit
+			prompt.show()
+		}
+	} else if character, found := cutPrefix(input, ":kanji "); found {
+		prompt.showKanji(character)
+	} else if components, found := cutPrefix(input, ":radicals "); found {
+		prompt.findRadicals(strings.Fields(components))
+	} else if word, found := cutPrefix(input, ":examples "); found {
+		prompt.showExamples(word)
+	} else if value, found := cutPrefix(input, ":pick "); found {
+		if number, err := strconv.Atoi(value); err != nil || number < 1 || number > len(prompt.picks) {
+			fmt.Printf("Pick one of the %v kanji listed by the last :radicals search\n\n", len(prompt.picks))
+		} else {
+			prompt.search(prompt.picks[number-1], wordsearch.Options{})
+		}
+	} else if value, found := cutPrefix(input, ":fold "); found {
+		if value != "on" && value != "off" {
+			fmt.Printf("Type :fold on or :fold off\n\n")
+		} else {
+			prompt.fold = value == "on"
+			fmt.Printf("Script folding is now %v\n\n", value)
+		}
+	} else if name, found := cutPrefix(input, ":format "); found {
+		if selected, err := cmdoutput.NewFormatter(name); err != nil {
+			fmt.Printf("%v\n\n", err)
+		} else {
+			prompt.formatter = selected
+			fmt.Printf("Results will now be shown as %v\n\n", name)
+		}
+	} else {
+		query, options, err := parseQuery(input)
+		if err != nil {
+			fmt.Printf("%v\n\n", err)
+			return
+		}
+		prompt.search(query, options)
+	}
+}
+
+func (prompt *session) search(query string, options wordsearch.Options) {
+	options.FoldKana = prompt.fold
+	prompt.query = query
+	prompt.results = wordsearch.Search(*prompt.table, query, options)
+	prompt.offset = 0
+	prompt.show()
+}
+
+// findRadicals lists the kanji made of the given radicals, which can then be searched for with :pick
+func (prompt *session) findRadicals(components []string) {
+	if prompt.table.Radicals == nil {
+		fmt.Printf("%v\n\n", radicals.MissingMessage)
+		return
+	}
+	strokes := kanjidic.StrokeCounts(prompt.table.Kanjidic)
+	found, err := prompt.table.Radicals.Find(components, func(kanji string) int { return strokes[kanji] })
+	if err != nil {
+		fmt.Printf("%v\n\n", err)
+		return
+	}
+	prompt.picks = found
+	cmdoutput.PrintRadicalSearch(os.Stdout, found, strokes, prompt.table.Radicals.Remaining(found, components))
+	if len(found) != 0 {
+		fmt.Printf("Type :pick <number> to search for one of them\n\n")
+	}
+}
+
+func (prompt *session) show() {
+	if prompt.query == "" {
+		fmt.Printf("Nothing was searched yet\n\n")
+		return
+	}
+	fmt.Printf("You searched for '%v'\n\n", prompt.query)
+	if info, found := kanjidic.Lookup(prompt.table.Kanjidic, prompt.table.KanjiIndex, prompt.query); found && prompt.offset == 0 {
+		cmdoutput.FormatKanji(os.Stdout, prompt.formatter, info) // A single kanji is shown on top of the words written with it
+	}
+	page := wordsearch.Paginate(prompt.results, prompt.offset, prompt.limit)
+	prompt.formatter.Format(os.Stdout, *prompt.table, page, prompt.query)
+	if page.HasNext() {
+		fmt.Printf("Type :next to see the following results\n\n")
+	}
+}
+
+func (prompt *session) showKanji(character string) {
+	if !kanjidic.IsSingleKanji(character) {
+		fmt.Printf("Type a single kanji after :kanji\n\n")
+		return
+	} else if prompt.table.Kanjidic == nil {
+		fmt.Printf("%v\n\n", kanjidic.MissingMessage)
+		return
+	}
+	info, found := kanjidic.Lookup(prompt.table.Kanjidic, prompt.table.KanjiIndex, character)
+	if !found {
+		fmt.Printf("%v is not in KANJIDIC2\n\n", character)
+		return
+	}
+	cmdoutput.FormatKanji(os.Stdout, prompt.formatter, info)
+}
+
+func (prompt *session) showExamples(word string) {
+	if prompt.table.Examples == nil {
+		fmt.Printf("%v\n\n", examples.MissingMessage)
+		return
+	}
+	result, sentences, found := wordsearch.Examples(*prompt.table, word, 0)
+	if !found {
+		fmt.Printf("No word matches '%v'\n\n", word)
+		return
+	}
+	cmdoutput.FormatExamples(os.Stdout, prompt.formatter, cmdoutput.Headword(prompt.table.Dict.Entries[result.Entry.WordID]), sentences)
+}
+
+// A query can start with ":en" or ":ro" to force Latin letters to be read as English or as romaji, e.g. ":ro kaki"
+// ":names" only searches proper names, and ":names:place" (or surname, given, company, person, product) only names of that type
+func parseQuery(input string) (string, wordsearch.Options, error) {
+	var options wordsearch.Options
+	var err error
+	if strings.HasPrefix(input, ":names") {
+		prefix, query, _ := strings.Cut(input, " ")
+		options.Dictionary = wordsearch.DictionaryNames
+		options.NameType, err = wordsearch.ParseNameType(strings.TrimPrefix(strings.TrimPrefix(prefix, ":names"), ":"))
+		return strings.TrimSpace(query), options, err
+	}
+	if query, found := cutPrefix(input, ":en "); found {
+		options.Script = wordsearch.ScriptEnglish
+		return query, options, nil
+	} else if query, found := cutPrefix(input, ":ro "); found {
+		options.Script = wordsearch.ScriptRomaji
+		return query, options, nil
+	}
+	return input, options, nil
+}
+
+func cutPrefix(input, prefix string) (string, bool) {
+	if !strings.HasPrefix(input, prefix) {
+		return input, false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(input, prefix)), true
+}
@@ -0,0 +1,136 @@
+package normalize
+
+// This package brings the different ways of writing the same Japanese text to a single form, so that text copied from PDFs and websites
+// finds the same words as text typed with an IME. The grids are built from normalized kanji forms, readings and glosses, and queries are
+// normalized the same way before they are looked up
+// It covers what NFKC does for Japanese text and a little more:
+//	full-width Latin letters, digits and symbols (ＡＢＣ, ３) become ASCII, and the ideographic space a regular one
+//	half-width katakana (ｶﾀｶﾅ) become full-width, their separate ﾞ and ﾟ joined with the kana before them
+//	kana followed by a dakuten or handakuten, combining (か + U+3099) or not (か + ゛), are composed into one character (が)
+//	iteration marks are written out: 時々 becomes 時時, いすゞ becomes いすず and コヽロ becomes ココロ
+
+import (
+	"japp/kana"
+)
+
+const (
+	combiningDakuten    = '゙'
+	combiningHandakuten = '゚'
+	dakuten             = '゛'
+	handakuten          = '゜'
+	kanjiRepeat         = '々'
+	hiraganaRepeat      = 'ゝ'
+	hiraganaVoiced      = 'ゞ'
+	katakanaRepeat      = 'ヽ'
+	katakanaVoiced      = 'ヾ'
+)
+
+// halfWidth holds the full-width forms of the half-width katakana and punctuation from U+FF61 to U+FF9F, in order
+var halfWidth = []rune("。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン゙゚")
+
+// String normalizes the text as described above, leaving anything else untouched
+func String(text string) string {
+	var normalized []rune
+	characters := []rune(text)
+	for i := 0; i < len(characters); i++ {
+		character := foldWidth(characters[i])
+		last := len(normalized) - 1
+		switch {
+		case (character == combiningDakuten || character == dakuten) && last >= 0 && voiced(normalized[last]) != 0:
+			normalized[last] = voiced(normalized[last])
+		case (character == combiningHandakuten || character == handakuten) && last >= 0 && semiVoiced(normalized[last]) != 0:
+			normalized[last] = semiVoiced(normalized[last])
+		case character == kanjiRepeat && i+1 < len(characters) && characters[i+1] == kanjiRepeat && last >= 1 && isKanji(normalized[last-1]) && isKanji(normalized[last]):
+			normalized = append(normalized, normalized[last-1], normalized[last]) // 々々 repeats the two kanji before it, as in 部分々々
+			i++
+		case character == kanjiRepeat && last >= 0 && isKanji(normalized[last]):
+			normalized = append(normalized, normalized[last])
+		case (character == hiraganaRepeat || character == hiraganaVoiced) && last >= 0 && kana.IsHiragana(normalized[last]):
+			normalized = append(normalized, repeatKana(normalized[last], character == hiraganaVoiced))
+		case (character == katakanaRepeat || character == katakanaVoiced) && last >= 0 && kana.IsKatakana(normalized[last]):
+			normalized = append(normalized, repeatKana(normalized[last], character == katakanaVoiced))
+		default:
+			normalized = append(normalized, character)
+		}
+	}
+	return string(normalized)
+}
+
+// FoldKana normalizes the text and writes its katakana as hiragana, so that ネコ and ねこ become the same string
+func FoldKana(text string) string {
+	return kana.KatakanaToHiragana(String(text))
+}
+
+func foldWidth(character rune) rune {
+	switch {
+	case character >= '！' && character <= '～':
+		return character - '！' + '!'
+	case character == '　':
+		return ' '
+	case character >= '｡' && character <= 'ﾟ':
+		return halfWidth[character-'｡']
+	}
+	return character
+}
+
+// voiced gives the kana with a dakuten, 0 if there is none
+func voiced(character rune) rune {
+	switch character {
+	case 'う':
+		return 'ゔ'
+	case 'ウ':
+		return 'ヴ'
+	case 'ワ', 'ヰ', 'ヱ', 'ヲ':
+		return character + 'ヷ' - 'ワ'
+	case hiraganaRepeat, katakanaRepeat:
+		return character + 1
+	}
+	if base := unvoicedBase(character); base != 0 && base == character {
+		return character + 1
+	}
+	return 0
+}
+
+// semiVoiced gives the kana of the h row with a handakuten, 0 for the other ones
+func semiVoiced(character rune) rune {
+	for _, row := range []rune{'は', 'ハ'} {
+		if character >= row && character <= row+12 && (character-row)%3 == 0 {
+			return character + 2
+		}
+	}
+	return 0
+}
+
+// unvoicedBase gives the plain form of a kana of the k, s, t and h rows, 0 for any other character
+// In both scripts these rows list every kana followed by its voiced form (か が き ぎ), the h row adding the semi-voiced one (は ば ぱ),
+// except for the small っ and ッ that sit in the middle of the t row
+func unvoicedBase(character rune) rune {
+	for _, row := range []struct{ first, small, last, h rune }{{'か', 'っ', 'ど', 'は'}, {'カ', 'ッ', 'ド', 'ハ'}} {
+		switch {
+		case character >= row.first && character < row.small:
+			return character - (character-row.first)%2
+		case character > row.small && character <= row.last:
+			return character - (character-row.small-1)%2
+		case character >= row.h && character <= row.h+14:
+			return character - (character-row.h)%3
+		}
+	}
+	return 0
+}
+
+// repeatKana writes out ゝ and ヽ, which repeat the kana before them without its dakuten, and ゞ and ヾ, which repeat it with one
+func repeatKana(previous rune, withDakuten bool) rune {
+	if base := unvoicedBase(previous); base != 0 {
+		previous = base
+	}
+	if withDakuten && voiced(previous) != 0 {
+		return voiced(previous)
+	}
+	return previous
+}
+
+// isKanji tells whether 々 can repeat the character, covering the blocks of searchgrids.IsKanji with a few gaps filled in
+// The searchgrids package can't be imported here, since it normalizes the words it indexes with this package
+func isKanji(character rune) bool {
+	return (character >= 0x3400 && character <= 0x9FFF) || (character >= 0xF900 && character <= 0xFAFF) || (character >= 0x20000 && character <= 0x323AF)
+}
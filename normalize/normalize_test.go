@@ -0,0 +1,40 @@
+package normalize
+
+import "testing"
+
+func TestString(t *testing.T) {
+	for _, test := range []struct{ text, normalized string }{
+		{"ｶﾞｯｺｳ", "ガッコウ"},
+		{"ﾊﾟﾝ", "パン"},
+		{"か゛", "が"},
+		{"が", "が"},
+		{"ぱ", "ぱ"},
+		{"ハ゜", "パ"},
+		{"う゛", "ゔ"},
+		{"部分々々", "部分部分"},
+		{"時々", "時時"},
+		{"いすゞ", "いすず"},
+		{"こゝろ", "こころ"},
+		{"コヽロ", "ココロ"},
+		{"バヽ", "バハ"},
+		{"ＷＡＴＥＲ", "WATER"},
+		{"３日", "3日"},
+		{"猫　犬", "猫 犬"},
+		// Marks with nothing to apply to are left as they are
+		{"々", "々"},
+		{"あ々", "あ々"},
+		{"ん゛", "ん゛"},
+		{"ゞ", "ゞ"},
+		{"ネコ", "ネコ"},
+	} {
+		if normalized := String(test.text); normalized != test.normalized {
+			t.Errorf("String(%q) = %q, expected %q", test.text, normalized, test.normalized)
+		}
+	}
+}
+
+func TestFoldKana(t *testing.T) {
+	if folded := FoldKana("ｶﾞｯｺｳ"); folded != "がっこう" {
+		t.Errorf("FoldKana(ｶﾞｯｺｳ) = %q, expected がっこう", folded)
+	}
+}
@@ -0,0 +1,165 @@
+package pitch
+
+// This package reads pitch accent data from a Kanjium-style TSV (accents.txt), where every line gives a word, its reading and its accent patterns:
+//	箸	はし	1
+//	橋	はし	2
+//	さくら		0
+// The reading is left empty for words written in kana only. A pattern is the position of the mora after which the pitch drops (the downstep),
+// 0 meaning that it never drops (heiban), and words with several accepted accents list them separated by commas
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MissingMessage explains what to do when the environment was built without the accent data
+const MissingMessage = "The pitch accent data is not loaded, put accents.txt into the data directory, it is loaded on the next start"
+
+// Accent is one line of the file: the word as written and its downsteps
+type Accent struct {
+	Word      string
+	Downsteps []int
+}
+
+// Index holds the accents by reading, since homophones like 箸 and 橋 only differ by their written form
+type Index struct {
+	Readings map[string][]Accent
+}
+
+// Pattern is the accent of a reading with a single downstep
+type Pattern struct {
+	Reading  string
+	Downstep int
+}
+
+func Load(reader io.Reader) (*Index, error) {
+	index := Index{Readings: make(map[string][]Accent)}
+	scanner := bufio.NewScanner(reader)
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %v: expected word, reading and accent separated by tabs", line_number)
+		}
+		word, reading := fields[0], fields[1]
+		if reading == "" {
+			reading = word
+		}
+		downsteps, err := parseDownsteps(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", line_number, err)
+		}
+		index.Readings[reading] = append(index.Readings[reading], Accent{Word: word, Downsteps: downsteps})
+	}
+	return &index, scanner.Err()
+}
+
+// parseDownsteps reads a list like "0,2", where a pattern can be preceded by a note on the part of speech it applies to, e.g. "(名)0,(副)1"
+func parseDownsteps(field string) ([]int, error) {
+	var downsteps []int
+	for _, pattern := range strings.Split(field, ",") {
+		pattern = strings.TrimLeftFunc(pattern, func(character rune) bool { return !unicode.IsDigit(character) })
+		downstep, err := strconv.Atoi(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("malformed accent %q", field)
+		}
+		downsteps = append(downsteps, downstep)
+	}
+	return downsteps, nil
+}
+
+// Lookup returns the patterns of the readings of a word, the word being identified by its written forms (none for words written in kana only)
+func (index *Index) Lookup(forms []string, readings []string) []Pattern {
+	if index == nil {
+		return nil
+	}
+	var patterns []Pattern
+	for _, reading := range readings {
+		for _, accent := range index.Readings[reading] {
+			if !matchesForm(accent.Word, reading, forms) {
+				continue
+			}
+			for _, downstep := range accent.Downsteps {
+				patterns = append(patterns, Pattern{Reading: reading, Downstep: downstep})
+			}
+			break
+		}
+	}
+	return patterns
+}
+
+func matchesForm(word, reading string, forms []string) bool {
+	if len(forms) == 0 {
+		return word == reading
+	}
+	for _, form := range forms {
+		if form == word {
+			return true
+		}
+	}
+	return false
+}
+
+// Morae splits a reading into morae, the small kana belonging to the one before them, e.g. "しゃしん" into "しゃ", "し", "ん"
+func Morae(reading string) []string {
+	var morae []string
+	for _, character := range reading {
+		if strings.ContainsRune("ゃゅょぁぃぅぇぉゎャュョァィゥェォヮ", character) && len(morae) != 0 {
+			morae[len(morae)-1] += string(character)
+		} else {
+			morae = append(morae, string(character))
+		}
+	}
+	return morae
+}
+
+// Contour writes the pitch of every mora as H or L, followed in parentheses by the pitch of a particle coming after the word
+// It tells heiban words (さくら LHH(H)) from the ones dropping on their last mora (おとこ LHH(L))
+func (pattern Pattern) Contour() string {
+	count := len(Morae(pattern.Reading))
+	var contour strings.Builder
+	for i := 1; i <= count+1; i++ {
+		if i == count+1 {
+			contour.WriteString("(")
+		}
+		if pattern.high(i) {
+			contour.WriteString("H")
+		} else {
+			contour.WriteString("L")
+		}
+	}
+	contour.WriteString(")")
+	return contour.String()
+}
+
+// high tells whether the mora at the given position, counted from 1, is high
+// The first mora is low unless the pitch drops right after it, the following ones are high until the downstep
+func (pattern Pattern) high(position int) bool {
+	if pattern.Downstep == 1 {
+		return position == 1
+	}
+	return position > 1 && (pattern.Downstep == 0 || position <= pattern.Downstep)
+}
+
+// Marked writes the reading with ＼ where the pitch drops, e.g. "は＼し", heiban readings being left as they are
+func (pattern Pattern) Marked() string {
+	morae := Morae(pattern.Reading)
+	if pattern.Downstep <= 0 || pattern.Downstep > len(morae) {
+		return pattern.Reading
+	}
+	return strings.Join(morae[:pattern.Downstep], "") + "＼" + strings.Join(morae[pattern.Downstep:], "")
+}
+
+// String gives the downstep number and the contour, e.g. "は＼し [1] HL(L)"
+func (pattern Pattern) String() string {
+	return fmt.Sprintf("%v [%v] %v", pattern.Marked(), pattern.Downstep, pattern.Contour())
+}
@@ -0,0 +1,120 @@
+package pitch
+
+import (
+	"strings"
+	"testing"
+)
+
+func sameInts(first, second []int) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseDownsteps(t *testing.T) {
+	for _, test := range []struct {
+		field     string
+		downsteps []int
+	}{
+		{"0", []int{0}},
+		{"1", []int{1}},
+		{"0,2", []int{0, 2}},
+		{"(名)0,(副)1", []int{0, 1}},
+		{"3, 0", []int{3, 0}},
+		{"10", []int{10}},
+	} {
+		if downsteps, err := parseDownsteps(test.field); err != nil || !sameInts(downsteps, test.downsteps) {
+			t.Errorf("parseDownsteps(%q) = %v, %v, expected %v", test.field, downsteps, err, test.downsteps)
+		}
+	}
+	for _, field := range []string{"", "heiban", "1,", "(名)"} {
+		if downsteps, err := parseDownsteps(field); err == nil {
+			t.Errorf("parseDownsteps(%q) = %v, expected an error", field, downsteps)
+		}
+	}
+}
+
+func TestMorae(t *testing.T) {
+	for _, test := range []struct {
+		reading string
+		morae   []string
+	}{
+		{"さくら", []string{"さ", "く", "ら"}},
+		{"きょう", []string{"きょ", "う"}},
+		{"しゃしん", []string{"しゃ", "し", "ん"}},
+		{"がっこう", []string{"が", "っ", "こ", "う"}},
+		{"ティーシャツ", []string{"ティ", "ー", "シャ", "ツ"}},
+	} {
+		if morae := Morae(test.reading); strings.Join(morae, "|") != strings.Join(test.morae, "|") {
+			t.Errorf("Morae(%q) = %v, expected %v", test.reading, morae, test.morae)
+		}
+	}
+}
+
+func TestContourAndMarked(t *testing.T) {
+	for _, test := range []struct {
+		reading  string
+		downstep int
+		contour  string
+		marked   string
+	}{
+		{"さくら", 0, "LHH(H)", "さくら"},      // heiban
+		{"はし", 1, "HL(L)", "は＼し"},        // atamadaka
+		{"はし", 2, "LH(L)", "はし＼"},        // odaka
+		{"おとこ", 3, "LHH(L)", "おとこ＼"},     // odaka
+		{"こころ", 2, "LHL(L)", "ここ＼ろ"},     // nakadaka
+		{"きょう", 1, "HL(L)", "きょ＼う"},      // atamadaka, きょ being a single mora
+		{"きょう", 0, "LH(H)", "きょう"},       // heiban
+		{"しゃしん", 0, "LHH(H)", "しゃしん"},    // heiban
+		{"りょこう", 2, "LHL(L)", "りょこ＼う"},   // nakadaka
+		{"きょうしつ", 0, "LHHH(H)", "きょうしつ"}, // heiban
+		{"いち", 5, "LH(H)", "いち"},         // a downstep past the end is left unmarked
+	} {
+		pattern := Pattern{Reading: test.reading, Downstep: test.downstep}
+		if contour := pattern.Contour(); contour != test.contour {
+			t.Errorf("the contour of %v [%v] is %v, expected %v", test.reading, test.downstep, contour, test.contour)
+		}
+		if marked := pattern.Marked(); marked != test.marked {
+			t.Errorf("%v [%v] is marked %v, expected %v", test.reading, test.downstep, marked, test.marked)
+		}
+	}
+}
+
+func TestLoadAndLookup(t *testing.T) {
+	index, err := Load(strings.NewReader("# word\treading\taccent\n箸\tはし\t1\n橋\tはし\t2\nさくら\t\t0\n今日\tきょう\t1\n上手\tじょうず\t3,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		forms, readings []string
+		patterns        []string
+	}{
+		{[]string{"箸"}, []string{"はし"}, []string{"は＼し [1] HL(L)"}},
+		{[]string{"橋"}, []string{"はし"}, []string{"はし＼ [2] LH(L)"}},
+		{nil, []string{"さくら"}, []string{"さくら [0] LHH(H)"}},
+		{[]string{"今日"}, []string{"きょう"}, []string{"きょ＼う [1] HL(L)"}},
+		{[]string{"上手"}, []string{"じょうず"}, []string{"じょうず＼ [3] LHH(L)", "じょうず [0] LHH(H)"}},
+		{[]string{"端"}, []string{"はし"}, nil},
+		{nil, []string{"はし"}, nil},
+	} {
+		var patterns []string
+		for _, pattern := range index.Lookup(test.forms, test.readings) {
+			patterns = append(patterns, pattern.String())
+		}
+		if strings.Join(patterns, "|") != strings.Join(test.patterns, "|") {
+			t.Errorf("Lookup(%v, %v) = %v, expected %v", test.forms, test.readings, patterns, test.patterns)
+		}
+	}
+	if _, err := Load(strings.NewReader("箸\tはし\n")); err == nil {
+		t.Errorf("Load accepted a line without an accent")
+	}
+	if _, err := Load(strings.NewReader("箸\tはし\tone\n")); err == nil {
+		t.Errorf("Load accepted a malformed accent")
+	}
+}
@@ -0,0 +1,207 @@
+package radicals
+
+// RADKFILE writes some radicals with a full kanji standing in for them, since the radical forms had no JIS code point, and a few with katakana (ノ, ハ)
+// radicalForms maps the forms people usually type to those stand-ins
+var radicalForms = map[string]string{
+	"丿": "ノ",
+	"八": "ハ",
+	"己": "已",
+	"亻": "化",
+	"𠆢": "个",
+	"丷": "并",
+	"刂": "刈",
+	"⻌": "込",
+	"辶": "込",
+	"⺌": "尚",
+	"忄": "忙",
+	"扌": "扎",
+	"氵": "汁",
+	"犭": "犯",
+	"艹": "艾",
+	"⺾": "艾",
+	"耂": "老",
+	"灬": "杰",
+	"礻": "礼",
+	"疒": "疔",
+	"衤": "初",
+	"罒": "買",
+}
+
+// radicalNames lets the common radicals be typed by their English meaning or their Japanese name in romaji
+var radicalNames = map[string]string{
+	"one":         "一",
+	"ichi":        "一",
+	"person":      "亻",
+	"ninben":      "亻",
+	"hitoyane":    "𠆢",
+	"legs":        "儿",
+	"hitoashi":    "儿",
+	"enter":       "入",
+	"eight":       "八",
+	"hachi":       "八",
+	"crown":       "冖",
+	"wakammuri":   "冖",
+	"ice":         "冫",
+	"nisui":       "冫",
+	"table":       "几",
+	"knife":       "刀",
+	"katana":      "刀",
+	"sword":       "刂",
+	"rittou":      "刂",
+	"power":       "力",
+	"chikara":     "力",
+	"wrap":        "勹",
+	"ten":         "十",
+	"juu":         "十",
+	"divination":  "卜",
+	"cliff":       "厂",
+	"gandare":     "厂",
+	"again":       "又",
+	"mata":        "又",
+	"mouth":       "口",
+	"kuchi":       "口",
+	"kuchihen":    "口",
+	"enclosure":   "囗",
+	"kunigamae":   "囗",
+	"earth":       "土",
+	"tsuchi":      "土",
+	"tsuchihen":   "土",
+	"scholar":     "士",
+	"evening":     "夕",
+	"big":         "大",
+	"dai":         "大",
+	"woman":       "女",
+	"onna":        "女",
+	"onnahen":     "女",
+	"child":       "子",
+	"ko":          "子",
+	"roof":        "宀",
+	"ukammuri":    "宀",
+	"inch":        "寸",
+	"small":       "小",
+	"corpse":      "尸",
+	"mountain":    "山",
+	"yama":        "山",
+	"river":       "川",
+	"kawa":        "川",
+	"work":        "工",
+	"oneself":     "己",
+	"cloth":       "巾",
+	"madare":      "广",
+	"bow":         "弓",
+	"yumi":        "弓",
+	"step":        "彳",
+	"gyouninben":  "彳",
+	"heart":       "心",
+	"kokoro":      "心",
+	"risshinben":  "忄",
+	"hand":        "手",
+	"te":          "手",
+	"tehen":       "扌",
+	"spear":       "戈",
+	"door":        "戸",
+	"strike":      "攵",
+	"script":      "文",
+	"axe":         "斤",
+	"direction":   "方",
+	"sun":         "日",
+	"hihen":       "日",
+	"moon":        "月",
+	"tsuki":       "月",
+	"tree":        "木",
+	"ki":          "木",
+	"kihen":       "木",
+	"lack":        "欠",
+	"stop":        "止",
+	"death":       "歹",
+	"water":       "氵",
+	"mizu":        "水",
+	"sanzui":      "氵",
+	"fire":        "火",
+	"renga":       "灬",
+	"cow":         "牛",
+	"ushi":        "牛",
+	"dog":         "犬",
+	"inu":         "犬",
+	"kemonohen":   "犭",
+	"jewel":       "王",
+	"tamahen":     "王",
+	"field":       "田",
+	"ta":          "田",
+	"sickness":    "疒",
+	"yamaidare":   "疒",
+	"white":       "白",
+	"dish":        "皿",
+	"eye":         "目",
+	"me":          "目",
+	"stone":       "石",
+	"ishi":        "石",
+	"altar":       "礻",
+	"shimesuhen":  "礻",
+	"grain":       "禾",
+	"nogihen":     "禾",
+	"hole":        "穴",
+	"stand":       "立",
+	"bamboo":      "竹",
+	"take":        "竹",
+	"takekammuri": "竹",
+	"rice":        "米",
+	"kome":        "米",
+	"thread":      "糸",
+	"ito":         "糸",
+	"itohen":      "糸",
+	"net":         "罒",
+	"sheep":       "羊",
+	"feather":     "羽",
+	"ear":         "耳",
+	"mimi":        "耳",
+	"meat":        "肉",
+	"self":        "自",
+	"grass":       "艹",
+	"kusakammuri": "艹",
+	"insect":      "虫",
+	"mushi":       "虫",
+	"clothes":     "衣",
+	"koromohen":   "衤",
+	"see":         "見",
+	"horn":        "角",
+	"speech":      "言",
+	"gonben":      "言",
+	"shell":       "貝",
+	"kai":         "貝",
+	"run":         "走",
+	"foot":        "足",
+	"ashi":        "足",
+	"body":        "身",
+	"vehicle":     "車",
+	"kuruma":      "車",
+	"walk":        "辶",
+	"shinnyou":    "辶",
+	"village":     "里",
+	"metal":       "金",
+	"kane":        "金",
+	"kanehen":     "金",
+	"gate":        "門",
+	"mon":         "門",
+	"rain":        "雨",
+	"ame":         "雨",
+	"blue":        "青",
+	"face":        "面",
+	"leather":     "革",
+	"page":        "頁",
+	"wind":        "風",
+	"kaze":        "風",
+	"eat":         "食",
+	"shokuhen":    "食",
+	"head":        "首",
+	"horse":       "馬",
+	"uma":         "馬",
+	"bone":        "骨",
+	"tall":        "高",
+	"hair":        "髟",
+	"demon":       "鬼",
+	"fish":        "魚",
+	"sakana":      "魚",
+	"bird":        "鳥",
+	"tori":        "鳥",
+}
@@ -0,0 +1,178 @@
+package radicals
+
+// This package finds kanji by the radicals they are made of, using RADKFILE (radical -> kanji) and KRADFILE (kanji -> radicals) from the EDRDG
+// Both files are distributed in EUC-JP and have to be converted to UTF-8 first, e.g. with 'iconv -f EUC-JP -t UTF-8 radkfile > ~/.local/share/japp/radkfile'
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Radical struct {
+	Character string
+	Strokes   int
+	Kanji     []string // Every kanji containing the radical, in the order of RADKFILE
+}
+
+// Index holds both files: the radicals in the order of their stroke count and the radicals every kanji is made of
+type Index struct {
+	Radicals   []Radical
+	Components map[string][]string
+}
+
+// MissingMessage explains what to do when the environment was built without RADKFILE
+const MissingMessage = "RADKFILE is not loaded, put the UTF-8 versions of radkfile and kradfile into the data directory, they are loaded on the next start"
+
+var ErrNotUTF8 = errors.New("the file is not UTF-8, convert it with 'iconv -f EUC-JP -t UTF-8'")
+
+// LoadRadkfile reads RADKFILE, where each radical is announced by a line like "$ 口 3" and followed by lines of the kanji containing it
+func LoadRadkfile(reader io.Reader) ([]Radical, error) {
+	var radicals []Radical
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !utf8.ValidString(line) {
+			return nil, ErrNotUTF8
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "$") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("malformed radical line %q", line)
+			}
+			strokes, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed stroke count in %q", line)
+			}
+			radicals = append(radicals, Radical{Character: fields[1], Strokes: strokes})
+			continue
+		}
+		if len(radicals) == 0 {
+			return nil, fmt.Errorf("kanji listed before the first radical: %q", line)
+		}
+		current := &radicals[len(radicals)-1]
+		for _, kanji := range strings.TrimSpace(line) {
+			current.Kanji = append(current.Kanji, string(kanji))
+		}
+	}
+	return radicals, scanner.Err()
+}
+
+// LoadKradfile reads KRADFILE, made of lines like "語 : 言 五 口"
+func LoadKradfile(reader io.Reader) (map[string][]string, error) {
+	components := make(map[string][]string)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !utf8.ValidString(line) {
+			return nil, ErrNotUTF8
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kanji, parts, found := strings.Cut(line, " : ")
+		if !found {
+			return nil, fmt.Errorf("malformed kanji line %q", line)
+		}
+		components[strings.TrimSpace(kanji)] = strings.Fields(parts)
+	}
+	return components, scanner.Err()
+}
+
+// Resolve turns what the user typed into a radical of RADKFILE: the radical itself, one of its usual Unicode forms, or its English or Japanese name
+// The character is looked up as it is before its stand-in of radicalForms, which only applies when RADKFILE doesn't have it
+func (index *Index) Resolve(component string) (Radical, error) {
+	component = strings.ToLower(strings.TrimSpace(component))
+	if character, found := radicalNames[component]; found {
+		component = character
+	}
+	if radical, found := index.radical(component); found {
+		return radical, nil
+	}
+	if radical, found := index.radical(radicalForms[component]); found {
+		return radical, nil
+	}
+	return Radical{}, fmt.Errorf("unknown radical %q", component)
+}
+
+func (index *Index) radical(character string) (Radical, bool) {
+	for _, radical := range index.Radicals {
+		if radical.Character == character {
+			return radical, true
+		}
+	}
+	return Radical{}, false
+}
+
+// Find returns the kanji containing every one of the components, the simplest ones first
+// strokes gives the stroke count of a kanji, 0 meaning that it is unknown, in which case the kanji goes last
+func (index *Index) Find(components []string, strokes func(kanji string) int) ([]string, error) {
+	if len(components) == 0 {
+		return nil, errors.New("no radicals given")
+	}
+	var found []string
+	for i, component := range components {
+		radical, err := index.Resolve(component)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			found = append([]string{}, radical.Kanji...)
+		} else {
+			found = intersect(found, radical.Kanji)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		first, second := strokes(found[i]), strokes(found[j])
+		if first == 0 || second == 0 {
+			return second == 0 && first != 0
+		}
+		return first < second
+	})
+	return found, nil
+}
+
+// Remaining lists the radicals that can still be added to narrow the kanji down, taken from KRADFILE
+func (index *Index) Remaining(kanji []string, chosen []string) []string {
+	skip := make(map[string]bool)
+	for _, component := range chosen {
+		if radical, err := index.Resolve(component); err == nil {
+			skip[radical.Character] = true
+		}
+	}
+	present := make(map[string]bool)
+	for _, character := range kanji {
+		for _, component := range index.Components[character] {
+			present[component] = true
+		}
+	}
+	var remaining []string
+	for _, radical := range index.Radicals { // Going through RADKFILE keeps the radicals sorted by stroke count
+		if present[radical.Character] && !skip[radical.Character] {
+			remaining = append(remaining, radical.Character)
+		}
+	}
+	return remaining
+}
+
+func intersect(first, second []string) []string {
+	inSecond := make(map[string]bool)
+	for _, kanji := range second {
+		inSecond[kanji] = true
+	}
+	var result []string
+	for _, kanji := range first {
+		if inSecond[kanji] {
+			result = append(result, kanji)
+		}
+	}
+	return result
+}
@@ -0,0 +1,172 @@
+package radicals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const fragment = `# a RADKFILE fragment
+$ 口 3
+口古可
+$ 汁 3 js01
+汁江海
+$ 木 4
+木村林
+$ 十 2
+十古汁協
+`
+
+func TestLoadRadkfile(t *testing.T) {
+	radicals, err := LoadRadkfile(strings.NewReader("$ 口 3\n口古可\n語\n$ 十 2 js02\n十古\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(radicals) != 2 || radicals[0].Character != "口" || radicals[0].Strokes != 3 || radicals[1].Character != "十" || radicals[1].Strokes != 2 {
+		t.Fatalf("LoadRadkfile read %v", radicals)
+	}
+	if kanji := strings.Join(radicals[0].Kanji, ""); kanji != "口古可語" {
+		t.Errorf("口 has the kanji %v, expected 口古可語", kanji)
+	}
+	for _, malformed := range []string{"古可\n$ 口 3\n", "$ 口\n", "$ 口 three\n", "$ 口 3\n\xb8\xec\n"} {
+		if _, err := LoadRadkfile(strings.NewReader(malformed)); err == nil {
+			t.Errorf("LoadRadkfile accepted %q", malformed)
+		}
+	}
+	if _, err := LoadRadkfile(strings.NewReader("$ \xb8\xfd 3\n")); !errors.Is(err, ErrNotUTF8) {
+		t.Errorf("LoadRadkfile of an EUC-JP file gives %v, expected ErrNotUTF8", err)
+	}
+}
+
+func TestLoadKradfile(t *testing.T) {
+	components, err := LoadKradfile(strings.NewReader("# comment\n語 : 言 五 口\n古 : 十 口\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := strings.Join(components["語"], " "); parts != "言 五 口" || len(components) != 2 {
+		t.Errorf("LoadKradfile read %v", components)
+	}
+	if _, err := LoadKradfile(strings.NewReader("語 言 五 口\n")); err == nil {
+		t.Errorf("LoadKradfile accepted a line without a colon")
+	}
+}
+
+func fragmentIndex(t *testing.T) *Index {
+	radicals, err := LoadRadkfile(strings.NewReader(fragment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, err := LoadKradfile(strings.NewReader("古 : 十 口\n汁 : 汁 十\n協 : 十 力\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Index{Radicals: radicals, Components: components}
+}
+
+func TestResolve(t *testing.T) {
+	index := fragmentIndex(t)
+	for _, test := range []struct{ component, radical string }{
+		{"口", "口"},
+		{" 口 ", "口"},
+		{"mouth", "口"},
+		{"Kuchi", "口"},
+		{"十", "十"},
+		{"ten", "十"},
+		{"汁", "汁"},
+		{"氵", "汁"},
+		{"water", "汁"},
+		{"tree", "木"},
+	} {
+		if radical, err := index.Resolve(test.component); err != nil || radical.Character != test.radical {
+			t.Errorf("Resolve(%q) = %v, %v, expected %v", test.component, radical.Character, err, test.radical)
+		}
+	}
+	for _, component := range []string{"", "犬", "dog", "unknown"} {
+		if radical, err := index.Resolve(component); err == nil {
+			t.Errorf("Resolve(%q) = %v, expected an error", component, radical.Character)
+		}
+	}
+	// A radical that RADKFILE writes as it is goes before the stand-in of radicalForms
+	index.Radicals = append(index.Radicals, Radical{Character: "氵", Strokes: 3})
+	if radical, err := index.Resolve("氵"); err != nil || radical.Character != "氵" {
+		t.Errorf("Resolve(氵) = %v, %v, expected 氵 itself", radical.Character, err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	index := fragmentIndex(t)
+	strokes := map[string]int{"口": 3, "古": 5, "可": 5, "十": 2, "汁": 5, "協": 8}
+	for _, test := range []struct {
+		components []string
+		kanji      string
+	}{
+		{[]string{"口"}, "口古可"},
+		{[]string{"口", "十"}, "古"},
+		{[]string{"mouth", "ten"}, "古"},
+		{[]string{"十", "汁"}, "汁"},
+		{[]string{"十"}, "十古汁協"},
+		{[]string{"木", "口"}, ""},
+	} {
+		found, err := index.Find(test.components, func(kanji string) int { return strokes[kanji] })
+		if err != nil || strings.Join(found, "") != test.kanji {
+			t.Errorf("Find(%v) = %v, %v, expected %v", test.components, found, err, test.kanji)
+		}
+	}
+	for _, components := range [][]string{nil, {"口", "dog"}} {
+		if found, err := index.Find(components, func(string) int { return 0 }); err == nil {
+			t.Errorf("Find(%v) = %v, expected an error", components, found)
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	index := fragmentIndex(t)
+	if remaining := strings.Join(index.Remaining([]string{"古", "協"}, []string{"ten"}), " "); remaining != "口" {
+		t.Errorf("Remaining gives %v, expected 口", remaining)
+	}
+}
+
+// The radicals of RADKFILE in its order, as the $ lines give them. Some are written with a kanji or a katakana standing in for them
+var radkfileRadicals = [][]string{
+	1:  {"一", "｜", "丶", "ノ", "乙", "亅"},
+	2:  {"二", "亠", "人", "化", "个", "儿", "入", "ハ", "并", "冂", "冖", "冫", "几", "凵", "刀", "刈", "力", "勹", "匕", "匚", "十", "卜", "卩", "厂", "厶", "又", "マ", "九", "ユ", "乃", "乞"},
+	3:  {"込", "口", "囗", "土", "士", "夂", "夕", "大", "女", "子", "宀", "寸", "小", "尚", "尢", "尸", "屮", "山", "川", "工", "已", "巾", "干", "幺", "广", "廴", "廾", "弋", "弓", "ヨ", "彑", "彡", "彳", "忙", "扎", "汁", "犯", "艾", "邦", "阡", "也", "亡", "及", "久"},
+	4:  {"老", "心", "戈", "戸", "手", "支", "攵", "文", "斗", "斤", "方", "无", "日", "曰", "月", "木", "欠", "止", "歹", "殳", "比", "毛", "氏", "气", "水", "火", "杰", "爪", "父", "爻", "爿", "片", "牛", "犬", "礼", "王", "元", "井", "勿", "尤", "五", "屯", "巴", "毋"},
+	5:  {"玄", "瓦", "甘", "生", "用", "田", "疋", "疔", "癶", "白", "皮", "皿", "目", "矛", "矢", "石", "示", "禸", "禾", "穴", "立", "初", "世", "巨", "冊", "母", "買", "牙"},
+	6:  {"瓜", "竹", "米", "糸", "缶", "羊", "羽", "而", "耒", "耳", "聿", "肉", "自", "至", "臼", "舌", "舟", "艮", "色", "虍", "虫", "血", "行", "衣", "西"},
+	7:  {"臣", "見", "角", "言", "谷", "豆", "豕", "豸", "貝", "赤", "走", "足", "身", "車", "辛", "辰", "酉", "釆", "里", "舛", "麦"},
+	8:  {"金", "長", "門", "隶", "隹", "雨", "青", "非", "奄", "岡", "免", "斉"},
+	9:  {"面", "革", "韭", "音", "頁", "風", "飛", "食", "首", "香", "品"},
+	10: {"馬", "骨", "高", "髟", "鬥", "鬯", "鬲", "鬼", "竜", "韋"},
+	11: {"魚", "鳥", "鹵", "鹿", "麻", "亀", "滴", "黄", "黒"},
+	12: {"黍", "黹", "無", "歯"},
+	13: {"黽", "鼎", "鼓", "鼠"},
+	14: {"鼻", "齊"},
+	17: {"龠"},
+}
+
+// Every name and form of names.go has to resolve to a radical of RADKFILE once it is read by the parser
+func TestNamesAndFormsResolve(t *testing.T) {
+	var radkfile strings.Builder
+	for strokes, characters := range radkfileRadicals {
+		for _, character := range characters {
+			fmt.Fprintf(&radkfile, "$ %v %v\n%v\n", character, strokes, character)
+		}
+	}
+	radicals, err := LoadRadkfile(strings.NewReader(radkfile.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := &Index{Radicals: radicals}
+	for name, character := range radicalNames {
+		if _, err := index.Resolve(name); err != nil {
+			t.Errorf("the name %q stands for %v, which is not in RADKFILE: %v", name, character, err)
+		}
+	}
+	for form, standIn := range radicalForms {
+		if radical, err := index.Resolve(form); err != nil || radical.Character != standIn {
+			t.Errorf("the form %v resolves to %v, %v, expected its stand-in %v", form, radical.Character, err, standIn)
+		}
+	}
+}
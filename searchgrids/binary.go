@@ -0,0 +1,368 @@
+package searchgrids
+
+// The grids can be written to a binary file that is memory-mapped instead of decoded, so that starting the program doesn't have to rebuild them on the heap
+// All numbers are little endian. The file starts with a header:
+//	"JAPPGRID", format version (uint32), build ID (int64), number of grids (uint32), offset of every grid (uint64, 0 for a missing grid)
+// and every grid is laid out as:
+//	number of characters (uint32), number of WordIDs (uint32)
+//	key of every character in increasing order (uint32 each): its index in the alphabet for English and kana, its code point for kanji
+//	score of every WordID (int32 each)
+//	index of the first position of every character in the position table, plus one past the last (uint32 each)
+//	offset of the posting list of every position relative to the start of the posting lists, plus one past the last (uint32 each)
+//	posting lists, compressed as described in postings.go
+// Only the key and offset tables are read when a grid is opened, to check that they are in order and point inside the grid, so that a damaged file
+// is refused by LoadGrids instead of making a search panic. A posting list is only read when a search asks for it
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	binaryMagic   = "JAPPGRID"
+	binaryVersion = 4
+	headerSize    = len(binaryMagic) + 4 + 8 + 4
+)
+
+var ErrBadGrids = errors.New("malformed grid file")
+
+// MappedGrid is a grid read from the binary file, backed by the mapped bytes
+type MappedGrid struct {
+	data      []byte // The section of the grid, from its number of characters to its last posting list
+	chars     int
+	words     int
+	keys      int // Start of the table of character keys
+	starts    int // Start of the table giving the first position of every character
+	positions int // Start of the position table
+	postings  int // Start of the posting lists
+}
+
+// grid gives the characters of any of the alphabets, as increasing keys, with their positions
+func (alphabet *EngAlphabet) grid() ([]int, [][]Position) {
+	var keys []int
+	var letters [][]Position
+	for char, letter := range alphabet.Alphabet {
+		keys = append(keys, char)
+		letters = append(letters, letter.Positions)
+	}
+	return keys, letters
+}
+
+func (alphabet *KanaAlphabet) grid() ([]int, [][]Position) {
+	var keys []int
+	var letters [][]Position
+	for char, letter := range alphabet.Alphabet {
+		keys = append(keys, char)
+		letters = append(letters, letter.Positions)
+	}
+	return keys, letters
+}
+
+func (alphabet *KanjiAlphabet) grid() ([]int, [][]Position) {
+	var keys []int
+	for char := range alphabet.Alphabet {
+		keys = append(keys, int(char))
+	}
+	sort.Ints(keys)
+	var symbols [][]Position
+	for _, char := range keys {
+		symbols = append(symbols, alphabet.Alphabet[rune(char)].Positions)
+	}
+	return keys, symbols
+}
+
+// Griddable is implemented by the three alphabets, so that they can be written to the same file
+type Griddable interface {
+	grid() ([]int, [][]Position)
+}
+
+// WriteGrids writes the grids in the binary format, nil ones being recorded as missing
+// The build ID is read back by LoadGrids, which lets the caller check that the file belongs to the rest of its data
+func WriteGrids(writer io.Writer, build int64, grids []Griddable) error {
+	var sections [][]byte
+	for _, grid := range grids {
+		if grid == nil || isNil(grid) {
+			sections = append(sections, nil)
+			continue
+		}
+		section, err := encodeGrid(grid.grid())
+		if err != nil {
+			return err
+		}
+		sections = append(sections, section)
+	}
+	header := bytes.NewBufferString(binaryMagic)
+	binary.Write(header, binary.LittleEndian, uint32(binaryVersion))
+	binary.Write(header, binary.LittleEndian, build)
+	binary.Write(header, binary.LittleEndian, uint32(len(sections)))
+	offset := uint64(headerSize + 8*len(sections))
+	for _, section := range sections {
+		if section == nil {
+			binary.Write(header, binary.LittleEndian, uint64(0))
+			continue
+		}
+		binary.Write(header, binary.LittleEndian, offset)
+		offset += uint64(len(section))
+	}
+	if _, err := writer.Write(header.Bytes()); err != nil {
+		return err
+	}
+	for _, section := range sections {
+		if _, err := writer.Write(section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNil catches the typed nil pointers that an interface holding a missing alphabet carries
+func isNil(grid Griddable) bool {
+	switch alphabet := grid.(type) {
+	case *EngAlphabet:
+		return alphabet == nil
+	case *KanaAlphabet:
+		return alphabet == nil
+	case *KanjiAlphabet:
+		return alphabet == nil
+	}
+	return false
+}
+
+func encodeGrid(keys []int, letters [][]Position) ([]byte, error) {
+	var starts, offsets []uint32
+	var scores []int32
+	var postings []byte
+	for _, positions := range letters {
+		starts = append(starts, uint32(len(offsets)))
+		for _, position := range positions {
+			if len(postings) > 1<<32-1 {
+				return nil, fmt.Errorf("grid too large for the binary format")
+			}
+			offsets = append(offsets, uint32(len(postings)))
+			postings = appendList(postings, position.List)
+			for _, entry := range position.List {
+				for len(scores) <= entry.WordID {
+					scores = append(scores, 0)
+				}
+				scores[entry.WordID] = entry.Score
+			}
+		}
+	}
+	starts = append(starts, uint32(len(offsets)))
+	offsets = append(offsets, uint32(len(postings)))
+	var section bytes.Buffer
+	binary.Write(&section, binary.LittleEndian, uint32(len(letters)))
+	binary.Write(&section, binary.LittleEndian, uint32(len(scores)))
+	for _, key := range keys {
+		binary.Write(&section, binary.LittleEndian, uint32(key))
+	}
+	binary.Write(&section, binary.LittleEndian, scores)
+	binary.Write(&section, binary.LittleEndian, starts)
+	binary.Write(&section, binary.LittleEndian, offsets)
+	section.Write(postings)
+	return section.Bytes(), nil
+}
+
+// LoadGrids opens the grids of a binary file without decoding their posting lists, missing grids being nil
+func LoadGrids(data []byte) (build int64, grids []*MappedGrid, err error) {
+	if len(data) < headerSize || string(data[:len(binaryMagic)]) != binaryMagic {
+		return 0, nil, ErrBadGrids
+	}
+	if version := binary.LittleEndian.Uint32(data[len(binaryMagic):]); version != binaryVersion {
+		return 0, nil, fmt.Errorf("grid file version %v, expected %v", version, binaryVersion)
+	}
+	build = int64(binary.LittleEndian.Uint64(data[len(binaryMagic)+4:]))
+	count := int(binary.LittleEndian.Uint32(data[len(binaryMagic)+12:]))
+	if len(data) < headerSize+8*count {
+		return 0, nil, ErrBadGrids
+	}
+	for i := 0; i < count; i++ {
+		start := binary.LittleEndian.Uint64(data[headerSize+8*i:])
+		end := uint64(len(data))
+		for j := i + 1; j < count; j++ { // A grid ends where the next present one starts
+			if next := binary.LittleEndian.Uint64(data[headerSize+8*j:]); next != 0 {
+				end = next
+				break
+			}
+		}
+		if start == 0 {
+			grids = append(grids, nil)
+			continue
+		} else if start > end || end > uint64(len(data)) {
+			return 0, nil, ErrBadGrids
+		}
+		grid, err := openGrid(data[start:end])
+		if err != nil {
+			return 0, nil, err
+		}
+		grids = append(grids, grid)
+	}
+	return build, grids, nil
+}
+
+func openGrid(data []byte) (*MappedGrid, error) {
+	if len(data) < 8 {
+		return nil, ErrBadGrids
+	}
+	grid := MappedGrid{data: data, chars: int(binary.LittleEndian.Uint32(data)), words: int(binary.LittleEndian.Uint32(data[4:]))}
+	grid.keys = 8
+	grid.starts = grid.keys + 4*grid.chars + 4*grid.words
+	grid.positions = grid.starts + 4*(grid.chars+1)
+	if len(data) < grid.positions {
+		return nil, ErrBadGrids
+	}
+	total := int(grid.uint32At(grid.starts + 4*grid.chars))
+	grid.postings = grid.positions + 4*(total+1)
+	if len(data) < grid.postings || grid.postings+int(grid.uint32At(grid.positions+4*total)) != len(data) {
+		return nil, ErrBadGrids
+	}
+	if !grid.increasing(grid.keys, grid.chars, true) || grid.uint32At(grid.starts) != 0 || !grid.increasing(grid.starts, grid.chars+1, false) ||
+		grid.uint32At(grid.positions) != 0 || !grid.increasing(grid.positions, total+1, false) {
+		return nil, ErrBadGrids
+	}
+	return &grid, nil
+}
+
+// increasing tells whether a table of the grid is in increasing order, strictly or not
+// Together with the first and last values checked by openGrid, this keeps every offset inside the grid
+func (grid *MappedGrid) increasing(table, length int, strictly bool) bool {
+	for i := 1; i < length; i++ {
+		previous, current := grid.uint32At(table+4*(i-1)), grid.uint32At(table+4*i)
+		if current < previous || (strictly && current == previous) {
+			return false
+		}
+	}
+	return true
+}
+
+func (grid *MappedGrid) uint32At(offset int) uint32 {
+	return binary.LittleEndian.Uint32(grid.data[offset:])
+}
+
+func (grid *MappedGrid) score(wordID int) int32 {
+	if wordID >= grid.words {
+		return 0
+	}
+	return int32(grid.uint32At(grid.keys + 4*grid.chars + 4*wordID))
+}
+
+// slot finds the index of a character in the tables from its key, -1 if the grid doesn't hold it
+func (grid *MappedGrid) slot(key int) int {
+	if key < 0 {
+		return -1
+	}
+	slot := sort.Search(grid.chars, func(i int) bool { return grid.uint32At(grid.keys+4*i) >= uint32(key) })
+	if slot == grid.chars || grid.uint32At(grid.keys+4*slot) != uint32(key) {
+		return -1
+	}
+	return slot
+}
+
+// PositionCount is the number of positions the character appears at
+func (grid *MappedGrid) PositionCount(char int) int {
+	slot := grid.slot(char)
+	if slot == -1 {
+		return 0
+	}
+	return int(grid.uint32At(grid.starts+4*(slot+1)) - grid.uint32At(grid.starts+4*slot))
+}
+
+// Postings returns the compressed posting list of a character at a position
+func (grid *MappedGrid) Postings(char, position int) PostingList {
+	slot := grid.slot(char)
+	if slot == -1 || position < 0 || position >= grid.PositionCount(char) {
+		return PostingList{}
+	}
+	index := int(grid.uint32At(grid.starts+4*slot)) + position
+	start := grid.postings + int(grid.uint32At(grid.positions+4*index))
+	end := grid.postings + int(grid.uint32At(grid.positions+4*(index+1)))
+	return readList(grid.data[start:end], grid)
+}
+
+// English, Kana and Kanji wrap the grid into the alphabet it was written from
+
+func (grid *MappedGrid) English() *EngAlphabet {
+	if grid == nil {
+		return nil
+	}
+	return &EngAlphabet{mapped: grid}
+}
+
+func (grid *MappedGrid) Kana() *KanaAlphabet {
+	if grid == nil {
+		return nil
+	}
+	return &KanaAlphabet{mapped: grid}
+}
+
+func (grid *MappedGrid) Kanji() *KanjiAlphabet {
+	if grid == nil {
+		return nil
+	}
+	return &KanjiAlphabet{mapped: grid}
+}
+
+// Postings and PositionCount read the alphabets the same way whether they were built in memory or mapped from the binary file
+
+func (alphabet *EngAlphabet) Postings(char, position int) PostingList {
+	if alphabet.mapped != nil {
+		return alphabet.mapped.Postings(char, position)
+	} else if char < 0 || char >= len(alphabet.Alphabet) || position < 0 || position >= len(alphabet.Alphabet[char].Positions) {
+		return PostingList{}
+	}
+	return PostingList{entries: alphabet.Alphabet[char].Positions[position].List}
+}
+
+func (alphabet *EngAlphabet) PositionCount(char int) int {
+	if alphabet.mapped != nil {
+		return alphabet.mapped.PositionCount(char)
+	} else if char < 0 || char >= len(alphabet.Alphabet) {
+		return 0
+	}
+	return len(alphabet.Alphabet[char].Positions)
+}
+
+func (alphabet *KanaAlphabet) Postings(char, position int) PostingList {
+	if alphabet.mapped != nil {
+		return alphabet.mapped.Postings(char, position)
+	} else if char < 0 || char >= len(alphabet.Alphabet) || position < 0 || position >= len(alphabet.Alphabet[char].Positions) {
+		return PostingList{}
+	}
+	return PostingList{entries: alphabet.Alphabet[char].Positions[position].List}
+}
+
+func (alphabet *KanaAlphabet) PositionCount(char int) int {
+	if alphabet.mapped != nil {
+		return alphabet.mapped.PositionCount(char)
+	} else if char < 0 || char >= len(alphabet.Alphabet) {
+		return 0
+	}
+	return len(alphabet.Alphabet[char].Positions)
+}
+
+// The kanji alphabet is keyed by code point
+func (alphabet *KanjiAlphabet) Postings(char, position int) PostingList {
+	if alphabet.mapped != nil {
+		return alphabet.mapped.Postings(char, position)
+	}
+	symbol, found := alphabet.Alphabet[rune(char)]
+	if !found || position < 0 || position >= len(symbol.Positions) {
+		return PostingList{}
+	}
+	return PostingList{entries: symbol.Positions[position].List}
+}
+
+func (alphabet *KanjiAlphabet) PositionCount(char int) int {
+	if alphabet.mapped != nil {
+		return alphabet.mapped.PositionCount(char)
+	}
+	symbol, found := alphabet.Alphabet[rune(char)]
+	if !found {
+		return 0
+	}
+	return len(symbol.Positions)
+}
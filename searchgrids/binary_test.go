@@ -0,0 +1,137 @@
+package searchgrids
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+func testEntry(kanji, reading string, glosses ...string) jmdict.JmdictEntry {
+	entry := jmdict.JmdictEntry{Readings: []jmdict.JmdictReading{{Reading: reading}}}
+	if kanji != "" {
+		entry.Kanji = []jmdict.JmdictKanji{{Expression: kanji}}
+	}
+	sense := jmdict.JmdictSense{}
+	for _, gloss := range glosses {
+		sense.Glossary = append(sense.Glossary, jmdict.JmdictGlossary{Content: gloss})
+	}
+	entry.Sense = []jmdict.JmdictSense{sense}
+	return entry
+}
+
+func testDict() jmdict.Jmdict {
+	return jmdict.Jmdict{Entries: []jmdict.JmdictEntry{
+		testEntry("猫", "ねこ", "cat"),
+		testEntry("食べる", "たべる", "to eat"),
+		testEntry("食べ物", "たべもの", "food", "something to eat"),
+		testEntry("", "コーヒー", "coffee"),
+		testEntry("猫舌", "ねこじた", "aversion to hot food"),
+	}}
+}
+
+// writeTestGrids writes the grids of the dictionary with a missing grid in the middle, as a missing optional dictionary leaves them
+func writeTestGrids(t *testing.T, dict jmdict.Jmdict) ([]Griddable, []byte) {
+	english, kana, kanji := GenerateAlphabets(dict)
+	grids := []Griddable{english, (*KanaAlphabet)(nil), kana, kanji}
+	var buffer bytes.Buffer
+	if err := WriteGrids(&buffer, 42, grids); err != nil {
+		t.Fatal(err)
+	}
+	return grids, buffer.Bytes()
+}
+
+func sameEntries(first, second EntryList) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i].WordID != second[i].WordID || first[i].Score != second[i].Score || len(first[i].Hash) != len(second[i].Hash) {
+			return false
+		}
+		for j := range first[i].Hash {
+			if first[i].Hash[j] != second[i].Hash[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readAll decodes every posting list of a mapped grid
+func readAll(grid *MappedGrid) {
+	for slot := 0; slot < grid.chars; slot++ {
+		key := int(grid.uint32At(grid.keys + 4*slot))
+		for position := 0; position < grid.PositionCount(key); position++ {
+			grid.Postings(key, position).Decode()
+		}
+	}
+}
+
+func TestGridsRoundTrip(t *testing.T) {
+	grids, data := writeTestGrids(t, testDict())
+	build, mapped, err := LoadGrids(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build != 42 {
+		t.Errorf("build ID %v, expected 42", build)
+	}
+	if len(mapped) != len(grids) || mapped[1] != nil {
+		t.Fatalf("%v grids loaded, expected %v with the second one missing", len(mapped), len(grids))
+	}
+	for i, grid := range grids {
+		if mapped[i] == nil {
+			continue
+		}
+		keys, letters := grid.grid()
+		for j, key := range keys {
+			if count := mapped[i].PositionCount(key); count != len(letters[j]) {
+				t.Errorf("grid %v, key %v: %v positions, expected %v", i, key, count, len(letters[j]))
+				continue
+			}
+			for position, written := range letters[j] {
+				if read := mapped[i].Postings(key, position).Decode(); !sameEntries(read, written.List) {
+					t.Errorf("grid %v, key %v, position %v: read %v, expected %v", i, key, position, read, written.List)
+				}
+			}
+		}
+		if mapped[i].PositionCount(1<<30) != 0 || mapped[i].Postings(1<<30, 0).Len() != 0 || mapped[i].Postings(keys[0], -1).Len() != 0 {
+			t.Errorf("grid %v answers for a key or position it doesn't hold", i)
+		}
+	}
+}
+
+func TestDamagedGrids(t *testing.T) {
+	_, data := writeTestGrids(t, testDict())
+	for length := 0; length < len(data); length++ {
+		if _, grids, err := LoadGrids(data[:length]); err == nil {
+			t.Errorf("a grid file cut at %v of %v bytes was opened", length, len(data))
+			for _, grid := range grids {
+				if grid != nil {
+					readAll(grid)
+				}
+			}
+		}
+	}
+	// Whatever byte is damaged, the file is either refused or read without panicking
+	for i := range data {
+		damaged := append([]byte{}, data...)
+		damaged[i] ^= 0xFF
+		_, grids, err := LoadGrids(damaged)
+		if err != nil {
+			continue
+		}
+		for _, grid := range grids {
+			if grid != nil {
+				readAll(grid)
+			}
+		}
+	}
+	damaged := append([]byte{}, data...)
+	damaged[len(binaryMagic)] = binaryVersion + 1
+	if _, _, err := LoadGrids(damaged); err == nil || errors.Is(err, ErrBadGrids) {
+		t.Errorf("a grid file of another version gives %v, expected a version error", err)
+	}
+}
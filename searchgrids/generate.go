@@ -0,0 +1,122 @@
+package searchgrids
+
+// The grids are built by one goroutine per processor, every one indexing its own range of WordIDs into its own grids
+// The shards are then joined in the order of their ranges, so that every list stays sorted by WordID exactly as if the entries had been indexed one by one
+
+import (
+	"runtime"
+	"sync"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// Progress is called while the grids are built with the number of entries indexed since its last call, possibly from several goroutines at once
+type Progress func(entries int)
+
+// progressStep is how many entries a shard indexes between two calls to Progress
+const progressStep = 1000
+
+type shard struct {
+	english EngAlphabet
+	kana    KanaAlphabet
+	kanji   KanjiAlphabet
+}
+
+// Generate builds the grids of the dictionary, out of words written backwards if suffix is set, reporting to progress if it isn't nil
+func Generate(dict jmdict.Jmdict, suffix bool, progress Progress) (*EngAlphabet, *KanaAlphabet, *KanjiAlphabet) {
+	count := runtime.GOMAXPROCS(0)
+	if count > len(dict.Entries) {
+		count = len(dict.Entries)
+	}
+	if count == 0 {
+		count = 1
+	}
+	shards := make([]shard, count)
+	var wait sync.WaitGroup
+	for i := range shards {
+		wait.Add(1)
+		go func(built *shard, start, end int) {
+			defer wait.Done()
+			built.index(dict.Entries[start:end], start, suffix, progress)
+		}(&shards[i], i*len(dict.Entries)/count, (i+1)*len(dict.Entries)/count)
+	}
+	wait.Wait()
+	return mergeShards(shards)
+}
+
+func (built *shard) index(entries []jmdict.JmdictEntry, first int, suffix bool, progress Progress) {
+	fillLetters(&built.english)
+	fillKana(&built.kana)
+	fillKanji(&built.kanji)
+	for i, entry := range entries {
+		wordID := first + i
+		score := ScoreEntry(entry).Total()
+		entry = normalizeEntry(entry)
+		if suffix {
+			entry = reverseEntry(entry)
+		}
+		engWrite(&built.english, entry, wordID, score)
+		kanaWrite(&built.kana, entry, wordID, score)
+		kanjiWrite(&built.kanji, entry, wordID, score)
+		if progress != nil && (i+1)%progressStep == 0 {
+			progress(progressStep)
+		}
+	}
+	if progress != nil && len(entries)%progressStep != 0 {
+		progress(len(entries) % progressStep)
+	}
+}
+
+// mergeShards joins the grids of the shards, one goroutine per alphabet
+func mergeShards(shards []shard) (*EngAlphabet, *KanaAlphabet, *KanjiAlphabet) {
+	var engAlphabet EngAlphabet
+	var kanaAlphabet KanaAlphabet
+	var kanjiAlphabet KanjiAlphabet
+	fillLetters(&engAlphabet)
+	fillKana(&kanaAlphabet)
+	fillKanji(&kanjiAlphabet)
+	var wait sync.WaitGroup
+	wait.Add(3)
+	go func() {
+		defer wait.Done()
+		for char := range engAlphabet.Alphabet {
+			for _, built := range shards {
+				engAlphabet.Alphabet[char].Positions = mergePositions(engAlphabet.Alphabet[char].Positions, built.english.Alphabet[char].Positions)
+			}
+		}
+	}()
+	go func() {
+		defer wait.Done()
+		for char := range kanaAlphabet.Alphabet {
+			for _, built := range shards {
+				kanaAlphabet.Alphabet[char].Positions = mergePositions(kanaAlphabet.Alphabet[char].Positions, built.kana.Alphabet[char].Positions)
+			}
+		}
+	}()
+	go func() {
+		defer wait.Done()
+		for _, built := range shards {
+			for char, symbol := range built.kanji.Alphabet {
+				merged, found := kanjiAlphabet.Alphabet[char]
+				if !found {
+					merged = &KanjiSymbol{}
+					kanjiAlphabet.Alphabet[char] = merged
+				}
+				merged.Positions = mergePositions(merged.Positions, symbol.Positions)
+			}
+		}
+	}()
+	wait.Wait()
+	return &engAlphabet, &kanaAlphabet, &kanjiAlphabet
+}
+
+// mergePositions appends the lists of a shard to the lists of the shards before it, which only hold lower WordIDs
+func mergePositions(merged, positions []Position) []Position {
+	for len(merged) < len(positions) {
+		merged = append(merged, Position{})
+	}
+	for i, position := range positions {
+		merged[i].List = append(merged[i].List, position.List...)
+	}
+	return merged
+}
@@ -0,0 +1,244 @@
+package searchgrids
+
+// Posting lists are stored compressed in the grid file. Every list is its number of entries (uvarint), a skip table if it holds more than skipInterval entries,
+// and its entries:
+//	WordID minus the WordID of the entry before it (uvarint), number of hashes (uvarint), first hash (uvarint), every next hash minus the one before it (varint)
+// The skip table is its size in bytes (uvarint) followed by an item for every block of skipInterval entries but the first:
+//	WordID of the last entry before the block minus the one of the item before (uvarint), offset of the block in the entries minus the one of the item before (uvarint)
+// The scores only depend on the entry, so they are kept once per grid in a table indexed by WordID instead of in every list
+// Searches walk the lists with a PostingIterator, which skips the hashes of the entries it doesn't stop at without decoding them,
+// and whole blocks of entries when seeking a WordID past them
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// skipInterval is the number of entries of a block of the skip table
+const skipInterval = 32
+
+// PostingList is the list of the entries having a character at a position, either decoded for grids built in memory or compressed for mapped ones
+type PostingList struct {
+	entries EntryList
+	data    []byte
+	skips   []byte // The skip table, without its size, nil for short lists
+	count   int
+	grid    *MappedGrid // Holds the score table of compressed lists
+}
+
+// readList reads a compressed list, returning an empty one if it is damaged
+func readList(data []byte, grid *MappedGrid) PostingList {
+	count, size := binary.Uvarint(data)
+	if size <= 0 || count > uint64(len(data)) { // Every entry takes at least two bytes, a larger count can only come from a damaged list
+		return PostingList{}
+	}
+	data = data[size:]
+	var skips []byte
+	if count > skipInterval {
+		length, size := binary.Uvarint(data)
+		if size <= 0 || length > uint64(len(data)-size) {
+			return PostingList{}
+		}
+		skips, data = data[size:size+int(length)], data[size+int(length):]
+	}
+	return PostingList{data: data, skips: skips, count: int(count), grid: grid}
+}
+
+// Len is the number of entries in the list
+func (list PostingList) Len() int {
+	if list.data == nil {
+		return len(list.entries)
+	}
+	return list.count
+}
+
+// Decode returns every entry of the list
+func (list PostingList) Decode() EntryList {
+	if list.data == nil {
+		return list.entries
+	}
+	decoded := make(EntryList, 0, list.count)
+	iterator := list.Iterator()
+	for iterator.Next() {
+		decoded = append(decoded, iterator.Entry())
+	}
+	return decoded
+}
+
+func (list PostingList) Iterator() PostingIterator {
+	return PostingIterator{list: list, index: -1}
+}
+
+// PostingIterator goes through a posting list in the order of the WordIDs
+type PostingIterator struct {
+	list    PostingList
+	index   int
+	offset  int
+	wordID  int
+	hashes  int  // Number of hashes of the current entry
+	pending bool // The hashes of the current entry haven't been read yet
+	skip    int  // Offset of the next item of the skip table
+	block   int  // Number of the block the last item read from the skip table leads to
+	base    int  // WordID of the last entry before that block
+	start   int  // Offset of that block in the entries
+}
+
+// Next moves to the following entry, returning false at the end of the list
+func (iterator *PostingIterator) Next() bool {
+	if iterator.index+1 >= iterator.list.Len() {
+		iterator.index = iterator.list.Len()
+		return false
+	}
+	iterator.index++
+	if iterator.list.data == nil {
+		iterator.wordID = iterator.list.entries[iterator.index].WordID
+		return true
+	}
+	if iterator.pending {
+		iterator.skipHashes()
+	}
+	delta := iterator.uvarint()
+	iterator.wordID += int(delta)
+	iterator.hashes = int(iterator.uvarint())
+	iterator.pending = true
+	return true
+}
+
+// Seek moves to the first entry whose WordID is at least the given one, returning false if there is none
+// Entries already passed are never gone back to, so the WordIDs sought have to increase
+func (iterator *PostingIterator) Seek(wordID int) bool {
+	if iterator.index >= iterator.list.Len() {
+		return false
+	} else if iterator.index >= 0 && iterator.wordID >= wordID {
+		return true
+	}
+	if iterator.list.data == nil {
+		entries, first := iterator.list.entries, iterator.index+1
+		iterator.index = first + sort.Search(len(entries)-first, func(i int) bool { return entries[first+i].WordID >= wordID }) - 1
+		return iterator.Next()
+	}
+	iterator.skipBefore(wordID)
+	for iterator.Next() {
+		if iterator.wordID >= wordID {
+			return true
+		}
+	}
+	return false
+}
+
+// skipBefore jumps to the last block whose entries before it all have a WordID lower than the given one, if it is ahead of the current entry
+// The iterator is left on the last entry before the block, without its hashes, so that Next reads the first entry of the block
+func (iterator *PostingIterator) skipBefore(wordID int) {
+	skips := iterator.list.skips
+	for iterator.skip < len(skips) {
+		base, size := binary.Uvarint(skips[iterator.skip:])
+		if size <= 0 {
+			iterator.skip = len(skips)
+			return
+		}
+		start, startSize := binary.Uvarint(skips[iterator.skip+size:])
+		if startSize <= 0 || start > uint64(len(iterator.list.data)-iterator.start) {
+			iterator.skip = len(skips)
+			return
+		}
+		if iterator.base+int(base) >= wordID {
+			return
+		}
+		iterator.skip += size + startSize
+		iterator.block++
+		iterator.base += int(base)
+		iterator.start += int(start)
+		if last := iterator.block*skipInterval - 1; last > iterator.index {
+			iterator.index, iterator.wordID, iterator.offset, iterator.pending = last, iterator.base, iterator.start, false
+		}
+	}
+}
+
+func (iterator *PostingIterator) WordID() int {
+	return iterator.wordID
+}
+
+// Entry decodes the current entry with its score and hashes
+func (iterator *PostingIterator) Entry() Entry {
+	if iterator.list.data == nil {
+		return iterator.list.entries[iterator.index]
+	}
+	entry := Entry{WordID: iterator.wordID, Score: iterator.list.grid.score(iterator.wordID)}
+	if !iterator.pending {
+		return entry // Hashes can only be read once, callers keep the entry instead of asking again
+	}
+	capacity := iterator.hashes
+	if remaining := len(iterator.list.data) - iterator.offset; capacity > remaining { // Every hash takes at least a byte
+		capacity = remaining
+	}
+	entry.Hash = make(Hash, 0, capacity)
+	var previous int64
+	for i := 0; i < iterator.hashes && iterator.offset < len(iterator.list.data); i++ {
+		if i == 0 {
+			previous = int64(iterator.uvarint())
+		} else {
+			previous += iterator.varint()
+		}
+		entry.Hash = append(entry.Hash, uint16(previous))
+	}
+	iterator.pending = false
+	return entry
+}
+
+func (iterator *PostingIterator) skipHashes() {
+	for i := 0; i < iterator.hashes && iterator.offset < len(iterator.list.data); i++ {
+		iterator.uvarint()
+	}
+	iterator.pending = false
+}
+
+// uvarint and varint read a number at the current offset
+// A damaged list, whose numbers overflow or run past its end, reads as zeros from there on instead of making the offset go backwards
+func (iterator *PostingIterator) uvarint() uint64 {
+	value, size := binary.Uvarint(iterator.list.data[iterator.offset:])
+	if size <= 0 {
+		iterator.offset = len(iterator.list.data)
+		return 0
+	}
+	iterator.offset += size
+	return value
+}
+
+func (iterator *PostingIterator) varint() int64 {
+	value, size := binary.Varint(iterator.list.data[iterator.offset:])
+	if size <= 0 {
+		iterator.offset = len(iterator.list.data)
+		return 0
+	}
+	iterator.offset += size
+	return value
+}
+
+// appendList compresses a list at the end of the buffer
+func appendList(buffer []byte, list EntryList) []byte {
+	var entries, skips []byte
+	previous, base, start := 0, 0, 0
+	for i, entry := range list {
+		if i > 0 && i%skipInterval == 0 {
+			skips = binary.AppendUvarint(skips, uint64(previous-base))
+			skips = binary.AppendUvarint(skips, uint64(len(entries)-start))
+			base, start = previous, len(entries)
+		}
+		entries = binary.AppendUvarint(entries, uint64(entry.WordID-previous))
+		previous = entry.WordID
+		entries = binary.AppendUvarint(entries, uint64(len(entry.Hash)))
+		for i, hash := range entry.Hash {
+			if i == 0 {
+				entries = binary.AppendUvarint(entries, uint64(hash))
+			} else {
+				entries = binary.AppendVarint(entries, int64(hash)-int64(entry.Hash[i-1]))
+			}
+		}
+	}
+	buffer = binary.AppendUvarint(buffer, uint64(len(list)))
+	if len(list) > skipInterval {
+		buffer = binary.AppendUvarint(buffer, uint64(len(skips)))
+		buffer = append(buffer, skips...)
+	}
+	return append(buffer, entries...)
+}
@@ -0,0 +1,99 @@
+package searchgrids
+
+import "testing"
+
+// testList builds a list of WordIDs spaced by three, its hashes going down as well as up so that their deltas are negative too
+func testList(size int) EntryList {
+	var list EntryList
+	for i := 0; i < size; i++ {
+		entry := Entry{WordID: 3*i + 1}
+		for j := 0; j < i%4; j++ {
+			entry.Hash = append(entry.Hash, uint16((i*37+j*1000)%65536))
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// compressed writes the list the way the grid file holds it, scores aside since the empty grid gives 0 for all of them
+func compressed(list EntryList) PostingList {
+	return readList(appendList(nil, list), &MappedGrid{})
+}
+
+func TestPostingListRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, skipInterval, skipInterval + 1, 10*skipInterval + 5} {
+		list := testList(size)
+		postings := compressed(list)
+		if postings.Len() != size {
+			t.Errorf("list of %v entries reads as %v", size, postings.Len())
+		}
+		if (postings.skips != nil) != (size > skipInterval) {
+			t.Errorf("list of %v entries has a skip table: %v", size, postings.skips != nil)
+		}
+		if decoded := postings.Decode(); !sameEntries(decoded, list) {
+			t.Errorf("list of %v entries decodes as %v", size, decoded)
+		}
+		// Going through the WordIDs only skips the hashes without reading them
+		iterator := postings.Iterator()
+		for i := 0; i < size; i++ {
+			if !iterator.Next() || iterator.WordID() != list[i].WordID {
+				t.Fatalf("list of %v entries: entry %v has WordID %v, expected %v", size, i, iterator.WordID(), list[i].WordID)
+			}
+		}
+		if iterator.Next() || iterator.Seek(0) {
+			t.Errorf("list of %v entries goes on past its end", size)
+		}
+	}
+}
+
+func TestPostingIteratorSeek(t *testing.T) {
+	list := testList(10*skipInterval + 5)
+	for _, postings := range []PostingList{compressed(list), {entries: list}} {
+		iterator := postings.Iterator()
+		// Every target is sought after the one before, a WordID between two entries leading to the next one
+		for _, target := range []struct{ wordID, index int }{
+			{0, 0}, {2, 1}, {5, 2}, {3 * skipInterval, skipInterval}, {3*skipInterval + 1, skipInterval},
+			{3*5*skipInterval - 1, 5 * skipInterval}, {3*(10*skipInterval) + 1, 10 * skipInterval}, {3*(10*skipInterval+4) + 1, 10*skipInterval + 4},
+		} {
+			if !iterator.Seek(target.wordID) {
+				t.Fatalf("seeking %v found nothing", target.wordID)
+			}
+			expected := list[target.index]
+			if iterator.WordID() != expected.WordID {
+				t.Errorf("seeking %v led to %v, expected %v", target.wordID, iterator.WordID(), expected.WordID)
+			} else if entry := iterator.Entry(); !sameEntries(EntryList{entry}, EntryList{expected}) {
+				t.Errorf("seeking %v gives the entry %v, expected %v", target.wordID, entry, expected)
+			}
+		}
+		if iterator.Seek(3*len(list) + 1) {
+			t.Errorf("seeking past the last entry found %v", iterator.WordID())
+		}
+	}
+}
+
+// The hashes of a compressed entry can only be read once, and the iterator has to go on from the right offset whether they were read or not
+func TestPostingIteratorEntryOnce(t *testing.T) {
+	list := testList(3 * skipInterval)
+	iterator := compressed(list).Iterator()
+	for i := range list {
+		iterator.Next()
+		if i%2 == 1 {
+			continue
+		}
+		first := iterator.Entry()
+		if !sameEntries(EntryList{first}, EntryList{list[i]}) {
+			t.Errorf("entry %v reads as %v, expected %v", i, first, list[i])
+		}
+		if again := iterator.Entry(); again.WordID != list[i].WordID || len(again.Hash) != 0 {
+			t.Errorf("entry %v read again gives %v, expected its WordID without hashes", i, again)
+		}
+	}
+}
+
+func TestSkipTableUsed(t *testing.T) {
+	iterator := compressed(testList(10 * skipInterval)).Iterator()
+	iterator.Seek(3 * 8 * skipInterval)
+	if iterator.block != 8 {
+		t.Errorf("seeking into block 8 read the skip table up to block %v", iterator.block)
+	}
+}
@@ -1,6 +1,7 @@
 package searchgrids
 
 import (
+	"japp/normalize"
 	"regexp"
 	"strings"
 
@@ -10,14 +11,17 @@ import (
 type EngAlphabet struct {
 	// Structure representing a slice of English alphabet characters. Each element is a struct corresponding to a letter and contains a slice of Position structs
 	Alphabet []EngLetter
+	mapped   *MappedGrid // Set instead of Alphabet when the grid comes from the binary file, see binary.go
 }
 
 type KanaAlphabet struct {
 	Alphabet []KanaLetter
+	mapped   *MappedGrid
 }
 
 type KanjiAlphabet struct {
-	Alphabet []KanjiSymbol
+	Alphabet map[rune]*KanjiSymbol // Keyed by code point, since kanji are spread over many blocks of Unicode, most of them outside the Basic Multilingual Plane
+	mapped   *MappedGrid
 }
 
 type EngLetter struct {
@@ -43,26 +47,68 @@ type EntryList []Entry
 
 type Entry struct {
 	WordID int
-	Score  uint16
+	Score  int32 // Part of the score that only depends on the entry, see ScoreEntry
 	Hash   Hash
 }
 
 type Hash []uint16
 
 func GenerateAlphabets(dict jmdict.Jmdict) (*EngAlphabet, *KanaAlphabet, *KanjiAlphabet) {
-	var engAlphabet EngAlphabet
-	var kanaAlphabet KanaAlphabet
-	var kanjiAlphabet KanjiAlphabet
-	fillLetters(&engAlphabet)
-	fillKana(&kanaAlphabet)
-	fillKanji(&kanjiAlphabet)
-	for wordID, entry := range dict.Entries {
-		score := scoreEntry(entry)
-		engWrite(&engAlphabet, entry, wordID, score)
-		kanaWrite(&kanaAlphabet, entry, wordID, score)
-		kanjiWrite(&kanjiAlphabet, entry, wordID, score)
+	return Generate(dict, false, nil)
+}
+
+// GenerateSuffixAlphabets builds the same grids out of words written backwards, so that finding the words ending with a query
+// is a lookup from position 0 just like finding the words starting with it
+func GenerateSuffixAlphabets(dict jmdict.Jmdict) (*EngAlphabet, *KanaAlphabet, *KanjiAlphabet) {
+	return Generate(dict, true, nil)
+}
+
+// normalizeEntry copies the kanji forms and readings of an entry in their normalized form, which is what queries are compared with
+// Glosses are normalized by ParseWords
+func normalizeEntry(entry jmdict.JmdictEntry) jmdict.JmdictEntry {
+	normalized := entry
+	normalized.Kanji = nil
+	for _, kanji := range entry.Kanji {
+		normalized.Kanji = append(normalized.Kanji, jmdict.JmdictKanji{Expression: normalize.String(kanji.Expression)})
+	}
+	normalized.Readings = nil
+	for _, reading := range entry.Readings {
+		normalized.Readings = append(normalized.Readings, jmdict.JmdictReading{Reading: normalize.String(reading.Reading)})
+	}
+	return normalized
+}
+
+// reverseEntry copies the parts of an entry that get indexed with every word written backwards
+// Glosses are reversed word by word, so that the words keep their order and their hashes stay the same as in the regular grid
+func reverseEntry(entry jmdict.JmdictEntry) jmdict.JmdictEntry {
+	var reversed jmdict.JmdictEntry
+	for _, kanji := range entry.Kanji {
+		reversed.Kanji = append(reversed.Kanji, jmdict.JmdictKanji{Expression: Reverse(kanji.Expression)})
+	}
+	for _, reading := range entry.Readings {
+		reversed.Readings = append(reversed.Readings, jmdict.JmdictReading{Reading: Reverse(reading.Reading)})
+	}
+	for _, sense := range entry.Sense {
+		var reversed_sense jmdict.JmdictSense
+		for _, gloss := range sense.Glossary {
+			words := ParseWords(gloss.Content)
+			for i, word := range words {
+				words[i] = Reverse(word)
+			}
+			reversed_sense.Glossary = append(reversed_sense.Glossary, jmdict.JmdictGlossary{Content: strings.Join(words, " ")})
+		}
+		reversed.Sense = append(reversed.Sense, reversed_sense)
+	}
+	return reversed
+}
+
+// Reverse writes a string backwards, character by character
+func Reverse(word string) string {
+	characters := []rune(word)
+	for i, j := 0, len(characters)-1; i < j; i, j = i+1, j-1 {
+		characters[i], characters[j] = characters[j], characters[i]
 	}
-	return &engAlphabet, &kanaAlphabet, &kanjiAlphabet
+	return string(characters)
 }
 
 func fillLetters(alphabet *EngAlphabet) {
@@ -78,30 +124,44 @@ func fillKana(alphabet *KanaAlphabet) {
 }
 
 func fillKanji(alphabet *KanjiAlphabet) {
-	for i := 0; i < 27503; i++ {
-		alphabet.Alphabet = append(alphabet.Alphabet, KanjiSymbol{})
-	}
+	alphabet.Alphabet = make(map[rune]*KanjiSymbol)
 }
 
-func scoreEntry(entry jmdict.JmdictEntry) uint16 {
-	score := checkKanji(entry.Kanji) + checkContent(entry) + checkReadings(entry)
-	score += 500
-	return score
+// EntryScore is the part of a word's score that doesn't depend on the query, computed once when the grids are built
+// Every component is a plain signed number, so penalties like restricted readings can't wrap around
+type EntryScore struct {
+	Base     int32 // Same for every entry, so that the total stays positive
+	Kanji    int32 // Number of kanji forms, weighted by their priority tags and how usual they are
+	Content  int32 // Amount of translations, which grows with the number of senses and glosses
+	Readings int32 // Number of readings, lowered by restricted readings
+}
+
+func (score EntryScore) Total() int32 {
+	return score.Base + score.Kanji + score.Content + score.Readings
+}
+
+func ScoreEntry(entry jmdict.JmdictEntry) EntryScore {
+	return EntryScore{
+		Base:     500,
+		Kanji:    int32(checkKanji(entry.Kanji)),
+		Content:  int32(checkContent(entry)),
+		Readings: int32(checkReadings(entry)),
+	}
 }
 
-func checkKanji(kanji []jmdict.JmdictKanji) uint16 {
-	var score uint16
+func checkKanji(kanji []jmdict.JmdictKanji) int {
+	var score int
 	length := len(kanji)
 	if length != 0 {
-		score += uint16(length) * 10
+		score += length * 10
 		for i := 0; i < length; i++ {
-			score = score + (uint16(length-i))*(kanjiFirst(kanji[i])-limitedKanji(kanji[i])+kanjiPriority(kanji[i]))
+			score = score + (length-i)*(kanjiFirst(kanji[i])-limitedKanji(kanji[i])+kanjiPriority(kanji[i]))
 		}
 	}
 	return score
 }
 
-func kanjiFirst(kanji_entry jmdict.JmdictKanji) uint16 {
+func kanjiFirst(kanji_entry jmdict.JmdictKanji) int {
 	for _, expression := range kanji_entry.Expression {
 		if IsKanji(expression) {
 			return 2
@@ -112,9 +172,9 @@ func kanjiFirst(kanji_entry jmdict.JmdictKanji) uint16 {
 	return 0
 }
 
-func limitedKanji(kanji_entry jmdict.JmdictKanji) uint16 {
+func limitedKanji(kanji_entry jmdict.JmdictKanji) int {
 	str := strings.Join(kanji_entry.Information, "")
-	var value uint16 = 0
+	var value int = 0
 	if str == "search-only kanji form" {
 		value = 1
 	} else if str == "rarely-used kanji form" {
@@ -123,8 +183,8 @@ func limitedKanji(kanji_entry jmdict.JmdictKanji) uint16 {
 	return value
 }
 
-func kanjiPriority(kanji_entry jmdict.JmdictKanji) uint16 {
-	var value uint16 = 0
+func kanjiPriority(kanji_entry jmdict.JmdictKanji) int {
+	var value int = 0
 	length := len(kanji_entry.Priorities)
 	if length != 0 {
 		for i := 0; i < length; i++ {
@@ -141,8 +201,8 @@ func kanjiPriority(kanji_entry jmdict.JmdictKanji) uint16 {
 	return value
 }
 
-func wordfreq(priority string) uint16 {
-	var value uint16 = 0
+func wordfreq(priority string) int {
+	var value int = 0
 	flag := 0
 	for _, char := range priority {
 		if char == 'n' && flag == 0 {
@@ -152,7 +212,7 @@ func wordfreq(priority string) uint16 {
 			flag++
 			continue
 		} else if char >= 48 && char <= 57 && flag == 2 {
-			value = value*10 + uint16(int(char)-48)
+			value = value*10 + (int(char) - 48)
 		} else {
 			value = 0
 			break
@@ -161,21 +221,21 @@ func wordfreq(priority string) uint16 {
 	return value
 }
 
-func checkContent(entry jmdict.JmdictEntry) uint16 {
-	var score uint16
+func checkContent(entry jmdict.JmdictEntry) int {
+	var score int
 	senses := len(entry.Sense)
 	for _, sense := range entry.Sense {
 		glossaries := len(sense.Glossary)
 		for _, gloss := range sense.Glossary {
-			score += uint16(senses * glossaries * len(gloss.Content))
+			score += senses * glossaries * len(gloss.Content)
 		}
 	}
 	return score / 10
 }
 
-func checkReadings(entry jmdict.JmdictEntry) uint16 {
-	var score uint16
-	score += uint16(len(entry.Readings) * 5)
+func checkReadings(entry jmdict.JmdictEntry) int {
+	var score int
+	score += len(entry.Readings) * 5
 	for _, reading := range entry.Readings {
 		if len(reading.Information) != 0 {
 			score += 2
@@ -210,30 +270,45 @@ func IsKatakana(letter rune) bool {
 	return false
 }
 
+// kanjiBlocks are the blocks of Unicode holding CJK ideographs
+var kanjiBlocks = []struct{ first, last rune }{
+	{0x3400, 0x4DBF},   // CJK Unified Ideographs Extension A
+	{0x4E00, 0x9FFF},   // CJK Unified Ideographs
+	{0xF900, 0xFAFF},   // CJK Compatibility Ideographs
+	{0x20000, 0x2A6DF}, // Extension B
+	{0x2A700, 0x2B73F}, // Extension C
+	{0x2B740, 0x2B81F}, // Extension D
+	{0x2B820, 0x2CEAF}, // Extension E
+	{0x2CEB0, 0x2EBEF}, // Extension F
+	{0x2EBF0, 0x2EE5F}, // Extension I
+	{0x2F800, 0x2FA1F}, // CJK Compatibility Ideographs Supplement
+	{0x30000, 0x3134F}, // Extension G
+	{0x31350, 0x323AF}, // Extension H
+}
+
 func IsKanji(letter rune) bool {
-	if IsRegularKanji(letter) {
-		return true
-	} else if IsRareKanji(letter) {
-		return true
+	for _, block := range kanjiBlocks {
+		if letter >= block.first && letter <= block.last {
+			return true
+		}
 	}
 	return false
 }
 
+// IsRegularKanji tells whether the kanji is in the main block of CJK Unified Ideographs, which holds nearly every kanji in use
 func IsRegularKanji(letter rune) bool {
-	if letter >= 19968 && letter <= 40879 {
+	if letter >= 0x4E00 && letter <= 0x9FFF {
 		return true
 	}
 	return false
 }
 
+// IsRareKanji tells whether the kanji is in one of the extension or compatibility blocks
 func IsRareKanji(letter rune) bool {
-	if letter >= 13312 && letter <= 19903 {
-		return true
-	}
-	return false
+	return IsKanji(letter) && !IsRegularKanji(letter)
 }
 
-func engWrite(alphabet *EngAlphabet, entry jmdict.JmdictEntry, wordID int, score uint16) {
+func engWrite(alphabet *EngAlphabet, entry jmdict.JmdictEntry, wordID int, score int32) {
 	for i, sense := range entry.Sense {
 		for j, gloss := range sense.Glossary {
 			words := ParseWords(gloss.Content)
@@ -247,35 +322,34 @@ func engWrite(alphabet *EngAlphabet, entry jmdict.JmdictEntry, wordID int, score
 	}
 }
 
-func kanaWrite(alphabet *KanaAlphabet, entry jmdict.JmdictEntry, wordID int, score uint16) {
+func kanaWrite(alphabet *KanaAlphabet, entry jmdict.JmdictEntry, wordID int, score int32) {
 	for index, reading := range entry.Readings {
 		writeKanaWord(alphabet, reading.Reading, wordID, score, uint16(index))
 	}
 }
 
-func kanjiWrite(alphabet *KanjiAlphabet, entry jmdict.JmdictEntry, wordID int, score uint16) {
+func kanjiWrite(alphabet *KanjiAlphabet, entry jmdict.JmdictEntry, wordID int, score int32) {
 	for index, kanji := range entry.Kanji {
 		writeKanjiSymbol(alphabet, kanji.Expression, wordID, score, uint16(index))
 	}
 }
 
 func ParseWords(content string) []string {
-	temp := regexp.MustCompile(`[^a-zA-Z ]+`).ReplaceAllString(content, "")
+	temp := regexp.MustCompile(`[^a-zA-Z ]+`).ReplaceAllString(normalize.String(content), "")
 	temp = strings.ToLower(temp)
 	parsed_words := strings.Split(temp, " ")
 	return parsed_words
 }
 
-func writeEngWord(alphabet *EngAlphabet, word string, wordID int, score, hash uint16) {
+func writeEngWord(alphabet *EngAlphabet, word string, wordID int, score int32, hash uint16) {
 	for position, letter := range word {
 		var char int = int(letter) - 97 // We make use of the ASCII representation to get the indash values of slices based on the letter, which helps us save some time
 		insertEngEntry(alphabet, char, position, wordID, score, hash)
 	}
 }
 
-func writeKanaWord(alphabet *KanaAlphabet, word string, wordID int, score, index uint16) {
-	for position, character := range word {
-		pos := position / 3
+func writeKanaWord(alphabet *KanaAlphabet, word string, wordID int, score int32, index uint16) {
+	for pos, character := range []rune(word) { // Positions count characters, whatever the length of their encoding
 		var char int
 		if IsKatakana(character) {
 			char = int(character) - 12448
@@ -288,24 +362,18 @@ func writeKanaWord(alphabet *KanaAlphabet, word string, wordID int, score, index
 	}
 }
 
-func writeKanjiSymbol(alphabet *KanjiAlphabet, word string, wordID int, score, index uint16) {
-	for position, character := range word {
-		pos := position / 3
-		var char int
+func writeKanjiSymbol(alphabet *KanjiAlphabet, word string, wordID int, score int32, index uint16) {
+	for pos, character := range []rune(word) { // Positions count characters, whatever the length of their encoding
 		if !IsKanji(character) {
 			continue
-		} else if IsRegularKanji(character) {
-			char = int(character) - 19968
-		} else {
-			char = int(character) + 7600
 		}
-		insertKanjiEntry(alphabet, char, pos, wordID, score, index)
+		insertKanjiEntry(alphabet, character, pos, wordID, score, index)
 	}
 }
 
 // This is the main function that will be called during JMdict mapping for search.
 // It takes the rune of the letter and its position within the word, the word's indash in JMdict and the alphabet struct.
-func insertEngEntry(grid *EngAlphabet, char, position, wordID int, score, hash uint16) {
+func insertEngEntry(grid *EngAlphabet, char, position, wordID int, score int32, hash uint16) {
 	length := len(grid.Alphabet[char].Positions) - 1 // We make sure that the slice for the letter has enough elements to at least match the position value
 	for position > length {                          // If it doesn't, we append more elements to the slice
 		grid.Alphabet[char].Positions = append(grid.Alphabet[char].Positions, Position{})
@@ -314,7 +382,7 @@ func insertEngEntry(grid *EngAlphabet, char, position, wordID int, score, hash u
 	sortAndInsert(&grid.Alphabet[char].Positions[position], wordID, score, hash)
 }
 
-func insertKanaEntry(grid *KanaAlphabet, char, position, wordID int, score, index uint16) {
+func insertKanaEntry(grid *KanaAlphabet, char, position, wordID int, score int32, index uint16) {
 	length := len(grid.Alphabet[char].Positions) - 1 // We make sure that the slice for the letter has enough elements to at least match the position value
 	for position > length {                          // If it doesn't, we append more elements to the slice
 		grid.Alphabet[char].Positions = append(grid.Alphabet[char].Positions, Position{})
@@ -323,17 +391,22 @@ func insertKanaEntry(grid *KanaAlphabet, char, position, wordID int, score, inde
 	sortAndInsert(&grid.Alphabet[char].Positions[position], wordID, score, index)
 }
 
-func insertKanjiEntry(grid *KanjiAlphabet, char, position, wordID int, score, index uint16) {
-	length := len(grid.Alphabet[char].Positions) - 1
+func insertKanjiEntry(grid *KanjiAlphabet, char rune, position, wordID int, score int32, index uint16) {
+	symbol, found := grid.Alphabet[char]
+	if !found {
+		symbol = &KanjiSymbol{}
+		grid.Alphabet[char] = symbol
+	}
+	length := len(symbol.Positions) - 1
 	for position > length {
-		grid.Alphabet[char].Positions = append(grid.Alphabet[char].Positions, Position{})
+		symbol.Positions = append(symbol.Positions, Position{})
 		length++
 	}
-	sortAndInsert(&grid.Alphabet[char].Positions[position], wordID, score, index)
+	sortAndInsert(&symbol.Positions[position], wordID, score, index)
 }
 
 // This functions performs the sorting (if necessary) of the entry list and inserts the new element
-func sortAndInsert(position *Position, wordID int, score, hash uint16) {
+func sortAndInsert(position *Position, wordID int, score int32, hash uint16) {
 	length := len(position.List)
 	var entry Entry
 	entry.WordID = wordID
@@ -0,0 +1,72 @@
+package searchgrids
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIsKanji(t *testing.T) {
+	for _, test := range []struct {
+		letter rune
+		kanji  bool
+	}{
+		{'猫', true},
+		{0x33FF, false}, // Square ㏿, just before Extension A
+		{0x3400, true},
+		{0x4DBF, true},
+		{0x4DC0, false}, // Yijing hexagram symbols, between Extension A and the main block
+		{0x4E00, true},
+		{0x9FFF, true},
+		{0xA000, false},
+		{0xF8FF, false},
+		{0xF900, true}, // 豈, the first compatibility ideograph
+		{0xFAFF, true},
+		{0x1FFFF, false},
+		{0x20000, true},
+		{0x20B9F, true}, // 𠮟, as in 𠮟る
+		{0x29E3D, true}, // 𩸽, the fish hokke
+		{0x2A6E0, false},
+		{0x323AF, true},
+		{0x323B0, false},
+		{'あ', false},
+		{'ア', false},
+		{'々', false},
+		{'a', false},
+	} {
+		if IsKanji(test.letter) != test.kanji {
+			t.Errorf("IsKanji(%U) = %v, expected %v", test.letter, !test.kanji, test.kanji)
+		}
+	}
+}
+
+// Kanji outside the Basic Multilingual Plane are keyed by their code point like any other, and take up a single position
+func TestNonBMPKanjiRoundTrip(t *testing.T) {
+	alphabet := &KanjiAlphabet{Alphabet: map[rune]*KanjiSymbol{}}
+	writeKanjiSymbol(alphabet, "𠮟る", 0, 10, 0)
+	writeKanjiSymbol(alphabet, "大𠮟り", 1, 20, 0)
+	writeKanjiSymbol(alphabet, "𩸽", 2, 30, 0)
+	var buffer bytes.Buffer
+	if err := WriteGrids(&buffer, 1, []Griddable{alphabet}); err != nil {
+		t.Fatal(err)
+	}
+	_, grids, err := LoadGrids(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kanji := range []*KanjiAlphabet{alphabet, grids[0].Kanji()} {
+		if count := kanji.PositionCount(0x20B9F); count != 2 {
+			t.Errorf("𠮟 appears at %v positions, expected 2", count)
+		}
+		for position, wordID := range []int{0, 1} {
+			if list := kanji.Postings(0x20B9F, position).Decode(); len(list) != 1 || list[0].WordID != wordID {
+				t.Errorf("𠮟 at position %v lists %v, expected word %v", position, list, wordID)
+			}
+		}
+		if list := kanji.Postings(0x29E3D, 0).Decode(); len(list) != 1 || list[0].WordID != 2 || list[0].Score != 30 {
+			t.Errorf("𩸽 lists %v, expected word 2 with a score of 30", list)
+		}
+		if kanji.PositionCount(0x20B9F&0xFFFF) != 0 {
+			t.Errorf("the code point of 𠮟 truncated to 16 bits is found")
+		}
+	}
+}
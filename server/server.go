@@ -0,0 +1,241 @@
+package server
+
+// This package keeps the environment loaded in a long-lived process and answers dictionary lookups over HTTP with JSON documents
+// Decoding the envfile takes seconds, so integrations like editor plugins should talk to this server instead of starting the binary for every lookup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"japp/cmdoutput"
+	"japp/env"
+	"japp/kanjidic"
+	"japp/wordsearch"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 200
+)
+
+type Server struct {
+	table   env.Environment
+	timeout time.Duration
+}
+
+type searchResponse struct {
+	Query   string                 `json:"query"`
+	Total   int                    `json:"total"`
+	Offset  int                    `json:"offset"`
+	Limit   int                    `json:"limit"`
+	Results []cmdoutput.JSONResult `json:"results"`
+}
+
+type entryResponse struct {
+	WordID int                 `json:"wordID"`
+	Entry  cmdoutput.JSONEntry `json:"entry"`
+}
+
+type kanjiResponse struct {
+	Kanji string                 `json:"kanji"`
+	Info  *cmdoutput.JSONKanji   `json:"info"` // null if KANJIDIC2 isn't loaded or doesn't have the kanji
+	Total int                    `json:"total"`
+	Words []cmdoutput.JSONResult `json:"words"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func New(table env.Environment, timeout time.Duration) *Server {
+	return &Server{table: table, timeout: timeout}
+}
+
+// Handler returns the routes of the API, every one of them limited to the server's timeout
+// The searches don't take a context and can't be interrupted: a request that times out gets its 503 right away,
+// but the search behind it keeps running until it is done and its result is thrown away
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", server.search)
+	mux.HandleFunc("/entry/", server.entry)
+	mux.HandleFunc("/kanji/", server.kanji)
+	return jsonTimeout(mux, server.timeout)
+}
+
+// jsonTimeout is http.TimeoutHandler answering with a JSON error. The handlers write their headers to a buffer of the TimeoutHandler,
+// which copies them over the ones set here when they finish in time, so the content type only applies to the timeout response
+func jsonTimeout(handler http.Handler, timeout time.Duration) http.Handler {
+	timed := http.TimeoutHandler(handler, timeout, `{"error":"request timed out"}`)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		timed.ServeHTTP(writer, request)
+	})
+}
+
+// ListenAndServe answers requests on the given address until the process receives SIGINT or SIGTERM,
+// at which point requests that are still running get the length of a timeout to finish
+func (server *Server) ListenAndServe(address string) error {
+	httpServer := &http.Server{
+		Addr:              address,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: server.timeout,
+		ReadTimeout:       server.timeout,
+		WriteTimeout:      server.timeout + time.Second,
+		IdleTimeout:       time.Minute,
+	}
+	failed := make(chan error, 1)
+	go func() {
+		log.Printf("listening on http://%v", address)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+		close(failed)
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case err := <-failed:
+		return err
+	case <-signals:
+	}
+	log.Printf("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), server.timeout)
+	defer cancel()
+	return httpServer.Shutdown(ctx)
+}
+
+// GET /search?q=&limit=&offset=&script=&match=&dict=&nameType=&foldKana=&examples=
+func (server *Server) search(writer http.ResponseWriter, request *http.Request) {
+	if !allowGet(writer, request) {
+		return
+	}
+	parameters := request.URL.Query()
+	query := strings.TrimSpace(parameters.Get("q"))
+	if query == "" {
+		writeError(writer, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+	limit, err := intParameter(parameters.Get("limit"), defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeError(writer, http.StatusBadRequest, fmt.Sprintf("limit must be a number between 1 and %v", maxLimit))
+		return
+	}
+	offset, err := intParameter(parameters.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeError(writer, http.StatusBadRequest, "offset must be a positive number")
+		return
+	}
+	var options wordsearch.Options
+	if options.Script, err = wordsearch.ParseScript(parameters.Get("script")); err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	if options.Match, err = wordsearch.ParseMatchMode(parameters.Get("match")); err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	if options.Dictionary, err = wordsearch.ParseDictionary(parameters.Get("dict")); err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	if options.NameType, err = wordsearch.ParseNameType(parameters.Get("nameType")); err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	if fold := parameters.Get("foldKana"); fold != "" {
+		if options.FoldKana, err = strconv.ParseBool(fold); err != nil {
+			writeError(writer, http.StatusBadRequest, "foldKana must be true or false")
+			return
+		}
+	}
+	examples, err := intParameter(parameters.Get("examples"), wordsearch.DefaultExamples)
+	if err != nil || examples < 0 {
+		writeError(writer, http.StatusBadRequest, "examples must be a positive number")
+		return
+	}
+	if examples == 0 {
+		examples = wordsearch.NoExamples
+	}
+	options.Offset, options.Limit, options.Examples = offset, limit, examples
+	page := wordsearch.SearchPage(server.table, query, options)
+	response := searchResponse{Query: query, Total: page.Total, Offset: page.Offset, Limit: page.Limit}
+	response.Results = cmdoutput.NewJSONResults(server.table, page)
+	writeJSON(writer, http.StatusOK, response)
+}
+
+// GET /entry/{wordID}
+func (server *Server) entry(writer http.ResponseWriter, request *http.Request) {
+	if !allowGet(writer, request) {
+		return
+	}
+	wordID, err := strconv.Atoi(strings.TrimPrefix(request.URL.Path, "/entry/"))
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "the WordID must be a number")
+		return
+	}
+	if wordID < 0 || wordID >= len(server.table.Dict.Entries) {
+		writeError(writer, http.StatusNotFound, fmt.Sprintf("no entry with WordID %v", wordID))
+		return
+	}
+	writeJSON(writer, http.StatusOK, entryResponse{WordID: wordID, Entry: cmdoutput.NewJSONEntry(server.table.Dict.Entries[wordID])})
+}
+
+// GET /kanji/{char} gives the KANJIDIC2 information about the kanji and lists the words written with it
+func (server *Server) kanji(writer http.ResponseWriter, request *http.Request) {
+	if !allowGet(writer, request) {
+		return
+	}
+	character := strings.TrimPrefix(request.URL.Path, "/kanji/")
+	if !kanjidic.IsSingleKanji(character) {
+		writeError(writer, http.StatusBadRequest, "expected a single kanji")
+		return
+	}
+	page := wordsearch.SearchPage(server.table, character, wordsearch.Options{Limit: defaultLimit})
+	response := kanjiResponse{Kanji: character, Total: page.Total}
+	if info, found := kanjidic.Lookup(server.table.Kanjidic, server.table.KanjiIndex, character); found {
+		converted := cmdoutput.NewJSONKanji(info)
+		response.Info = &converted
+	}
+	response.Words = cmdoutput.NewJSONResults(server.table, page)
+	writeJSON(writer, http.StatusOK, response)
+}
+
+func allowGet(writer http.ResponseWriter, request *http.Request) bool {
+	if request.Method == http.MethodGet || request.Method == http.MethodHead {
+		return true
+	}
+	writer.Header().Set("Allow", "GET, HEAD")
+	writeError(writer, http.StatusMethodNotAllowed, "only GET requests are supported")
+	return false
+}
+
+func intParameter(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeJSON(writer http.ResponseWriter, status int, document interface{}) {
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.WriteHeader(status)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		log.Printf("response encode: %v", err)
+	}
+}
+
+func writeError(writer http.ResponseWriter, status int, message string) {
+	writeJSON(writer, status, errorResponse{Error: message})
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"japp/env"
+	"japp/kanjidic"
+	"japp/searchgrids"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"foosoft.net/projects/jmdict"
+)
+
+func testServer() *Server {
+	var table env.Environment
+	table.Dict = &jmdict.Jmdict{Entries: []jmdict.JmdictEntry{
+		{
+			Kanji:    []jmdict.JmdictKanji{{Expression: "猫"}},
+			Readings: []jmdict.JmdictReading{{Reading: "ねこ"}},
+			Sense:    []jmdict.JmdictSense{{PartsOfSpeech: []string{"noun"}, Glossary: []jmdict.JmdictGlossary{{Content: "cat"}}}},
+		},
+		{
+			Readings: []jmdict.JmdictReading{{Reading: "コーヒー"}},
+			Sense:    []jmdict.JmdictSense{{PartsOfSpeech: []string{"noun"}, Glossary: []jmdict.JmdictGlossary{{Content: "coffee"}}}},
+		},
+		{
+			Kanji:    []jmdict.JmdictKanji{{Expression: "珈琲"}},
+			Readings: []jmdict.JmdictReading{{Reading: "こうひい"}},
+			Sense:    []jmdict.JmdictSense{{PartsOfSpeech: []string{"noun"}, Glossary: []jmdict.JmdictGlossary{{Content: "coffee"}}}},
+		},
+	}}
+	table.Forms = env.NewForms(table.Dict)
+	table.English, table.Kana, table.Kanji = searchgrids.GenerateAlphabets(*table.Dict)
+	table.EnglishSuffix, table.KanaSuffix, table.KanjiSuffix = searchgrids.GenerateSuffixAlphabets(*table.Dict)
+	table.Kanjidic = &jmdict.Kanjidic{Characters: []jmdict.KanjidicCharacter{{Literal: "猫"}}}
+	table.KanjiIndex = kanjidic.NewIndex(table.Kanjidic)
+	return New(table, time.Second)
+}
+
+// get sends a request through the handler of the server and decodes the JSON document it answers with
+func get(t *testing.T, server *Server, method, target string, document interface{}) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("%v %v answers with the content type %q", method, target, contentType)
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(document); err != nil {
+		t.Errorf("%v %v answers with a body that isn't JSON: %v", method, target, err)
+	}
+	return recorder.Code
+}
+
+func TestSearch(t *testing.T) {
+	server := testServer()
+	for _, test := range []struct {
+		target string
+		status int
+		total  int
+	}{
+		{"/search?q=ねこ", http.StatusOK, 1},
+		{"/search?q=こうひい", http.StatusOK, 1},
+		{"/search?q=こうひい&foldKana=true", http.StatusOK, 2},
+		{"/search?q=こうひい&foldKana=1", http.StatusOK, 2},
+		{"/search?q=こうひい&foldKana=false", http.StatusOK, 1},
+		{"/search?q=こうひい&foldKana=maybe", http.StatusBadRequest, 0},
+		{"/search", http.StatusBadRequest, 0},
+		{"/search?q=%20", http.StatusBadRequest, 0},
+		{"/search?q=ねこ&limit=0", http.StatusBadRequest, 0},
+		{"/search?q=ねこ&limit=201", http.StatusBadRequest, 0},
+		{"/search?q=ねこ&limit=ten", http.StatusBadRequest, 0},
+		{"/search?q=ねこ&offset=-1", http.StatusBadRequest, 0},
+		{"/search?q=ねこ&match=around", http.StatusBadRequest, 0},
+	} {
+		var response searchResponse
+		if status := get(t, server, http.MethodGet, test.target, &response); status != test.status {
+			t.Errorf("%v gives the status %v, expected %v", test.target, status, test.status)
+		} else if status == http.StatusOK && (response.Total != test.total || len(response.Results) != test.total) {
+			t.Errorf("%v gives %v results out of %v, expected %v", test.target, len(response.Results), response.Total, test.total)
+		}
+	}
+	var response searchResponse
+	if get(t, server, http.MethodGet, "/search?q=coffee&limit=1&offset=1", &response); response.Total != 2 || len(response.Results) != 1 || response.Limit != 1 || response.Offset != 1 {
+		t.Errorf("coffee with a limit of 1 gives %v results out of %v, at the offset %v", len(response.Results), response.Total, response.Offset)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	server := testServer()
+	var response entryResponse
+	if status := get(t, server, http.MethodGet, "/entry/1", &response); status != http.StatusOK || response.WordID != 1 {
+		t.Errorf("/entry/1 gives the status %v and the WordID %v", status, response.WordID)
+	}
+	for _, test := range []struct {
+		target string
+		status int
+	}{
+		{"/entry/3", http.StatusNotFound},
+		{"/entry/-1", http.StatusNotFound},
+		{"/entry/neko", http.StatusBadRequest},
+	} {
+		var failure errorResponse
+		if status := get(t, server, http.MethodGet, test.target, &failure); status != test.status || failure.Error == "" {
+			t.Errorf("%v gives the status %v and the error %q, expected the status %v", test.target, status, failure.Error, test.status)
+		}
+	}
+}
+
+func TestKanji(t *testing.T) {
+	server := testServer()
+	var response kanjiResponse
+	if status := get(t, server, http.MethodGet, "/kanji/猫", &response); status != http.StatusOK || response.Info == nil || response.Total != 1 {
+		t.Errorf("/kanji/猫 gives the status %v, the info %v and %v words", status, response.Info, response.Total)
+	}
+	response = kanjiResponse{}
+	if status := get(t, server, http.MethodGet, "/kanji/犬", &response); status != http.StatusOK || response.Info != nil || response.Total != 0 {
+		t.Errorf("/kanji/犬 gives the status %v, the info %v and %v words, expected nothing", status, response.Info, response.Total)
+	}
+	for _, target := range []string{"/kanji/ねこ", "/kanji/猫猫", "/kanji/"} {
+		var failure errorResponse
+		if status := get(t, server, http.MethodGet, target, &failure); status != http.StatusBadRequest {
+			t.Errorf("%v gives the status %v, expected %v", target, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestOnlyGet(t *testing.T) {
+	server := testServer()
+	for _, target := range []string{"/search?q=ねこ", "/entry/0", "/kanji/猫"} {
+		for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+			var failure errorResponse
+			if status := get(t, server, method, target, &failure); status != http.StatusMethodNotAllowed {
+				t.Errorf("%v %v gives the status %v, expected %v", method, target, status, http.StatusMethodNotAllowed)
+			}
+		}
+	}
+}
+
+func TestTimeoutIsJSON(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-release
+	})
+	recorder := httptest.NewRecorder()
+	jsonTimeout(slow, time.Millisecond).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/search?q=ねこ", nil))
+	var failure errorResponse
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("a request over the timeout gives the status %v, expected %v", recorder.Code, http.StatusServiceUnavailable)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("the timeout response has the content type %q", contentType)
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&failure); err != nil || failure.Error == "" {
+		t.Errorf("the timeout response %q isn't a JSON error: %v", recorder.Body.String(), err)
+	}
+}
@@ -0,0 +1,15 @@
+package wordsearch
+
+import (
+	"japp/env"
+	"japp/examples"
+)
+
+// Examples finds the word the query is most likely about, i.e. its best result among the words, and returns up to limit of its example sentences
+func Examples(table env.Environment, query string, limit int) (ResultEntry, []examples.Sentence, bool) {
+	results := Search(table, query, Options{Dictionary: DictionaryWords})
+	if len(results) == 0 {
+		return ResultEntry{}, nil, false
+	}
+	return results[0], table.Examples.For(results[0].Entry.WordID, limit), true
+}
@@ -0,0 +1,277 @@
+package wordsearch
+
+// Besides the default prefix search, a query can ask for words that are exactly the query, that end with it or that contain it anywhere
+// Suffixes are looked up in the grids built from words written backwards, and infixes by intersecting the regular grid at every starting position,
+// after which the candidates are checked against the equivalent wildcard pattern
+
+import (
+	"fmt"
+	"japp/env"
+	"japp/kana"
+	"japp/searchgrids"
+	"strings"
+)
+
+// MatchMode tells which part of a word the query has to match
+type MatchMode int
+
+const (
+	MatchPrefix   MatchMode = iota // The word starts with the query, which is the default
+	MatchExact                     // The word is the query
+	MatchSuffix                    // The word ends with the query
+	MatchContains                  // The query is anywhere in the word
+)
+
+// ParseMatchMode reads the name of a match mode: prefix, exact, suffix or contains
+func ParseMatchMode(name string) (MatchMode, error) {
+	switch name {
+	case "", "prefix":
+		return MatchPrefix, nil
+	case "exact":
+		return MatchExact, nil
+	case "suffix":
+		return MatchSuffix, nil
+	case "contains":
+		return MatchContains, nil
+	}
+	return MatchPrefix, fmt.Errorf("unknown match mode %q, expected prefix, exact, suffix or contains", name)
+}
+
+// matchMarkers reads the match mode from the query itself: "=水" is exact, "*学" a suffix, "*学*" anywhere in the word and "学*" a prefix
+// Queries with wildcards anywhere else are left alone and handled as patterns
+func matchMarkers(query string, mode MatchMode) (string, MatchMode) {
+	query = patternReplacer.Replace(strings.TrimSpace(query))
+	if strings.HasPrefix(query, "=") && !isPattern(query) {
+		return strings.TrimSpace(query[1:]), MatchExact
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(query, "*"), "*")
+	if inner == "" || isPattern(inner) {
+		return query, mode
+	}
+	leading, trailing := strings.HasPrefix(query, "*"), strings.HasSuffix(query, "*")
+	switch {
+	case leading && trailing:
+		return inner, MatchContains
+	case leading:
+		return inner, MatchSuffix
+	case trailing:
+		return inner, MatchPrefix
+	}
+	return query, mode
+}
+
+// modePattern writes a match mode as the wildcard pattern the results have to fit
+func modePattern(word string, mode MatchMode) []rune {
+	switch mode {
+	case MatchPrefix:
+		word = word + "*"
+	case MatchSuffix:
+		word = "*" + word
+	case MatchContains:
+		word = "*" + word + "*"
+	}
+	return []rune(word)
+}
+
+// japaneseGrids are the two grids of a script along with the way to get the indexed text of an entry back from a hash
+// backward is nil for dictionaries built without suffix grids (the names), whose suffix searches go through the forward grid instead
+type japaneseGrids struct {
+	forward  patternGrid
+	backward *patternGrid
+	text     func(wordID int, index uint16) string
+}
+
+func kanjiGrids(table env.Environment) japaneseGrids {
+	forms := func(wordID int) int { return len(table.Dict.Entries[wordID].Kanji) }
+	grids := japaneseGrids{
+		forward: patternGrid{
+			list: func(letter rune, position int) searchgrids.PostingList {
+				return kanjiEntryList(*table.Kanji, letter, position)
+			},
+			positions: kanjiPositionCount(*table.Kanji),
+			forms:     forms,
+		},
+		text: func(wordID int, index uint16) string {
+			return table.KanjiForm(wordID, int(index))
+		},
+	}
+	if table.KanjiSuffix != nil {
+		grids.backward = &patternGrid{
+			list: func(letter rune, position int) searchgrids.PostingList {
+				return kanjiEntryList(*table.KanjiSuffix, letter, position)
+			},
+			positions: kanjiPositionCount(*table.KanjiSuffix),
+			forms:     forms,
+		}
+	}
+	return grids
+}
+
+func kanaGrids(table env.Environment) japaneseGrids {
+	forms := func(wordID int) int { return len(table.Dict.Entries[wordID].Readings) }
+	grids := japaneseGrids{
+		forward: patternGrid{
+			list: func(letter rune, position int) searchgrids.PostingList {
+				return kanaEntryList(*table.Kana, letter, position)
+			},
+			positions: kanaPositionCount(*table.Kana),
+			forms:     forms,
+		},
+		text: func(wordID int, index uint16) string {
+			return table.Reading(wordID, int(index))
+		},
+	}
+	if table.KanaSuffix != nil {
+		grids.backward = &patternGrid{
+			list: func(letter rune, position int) searchgrids.PostingList {
+				return kanaEntryList(*table.KanaSuffix, letter, position)
+			},
+			positions: kanaPositionCount(*table.KanaSuffix),
+			forms:     forms,
+		}
+	}
+	return grids
+}
+
+func matchResults(table env.Environment, query string, mode MatchMode, options Options) ResultEntries {
+	if isKanjiQuery(query) {
+		return sortKanjiResults(table, japaneseMatchCandidates(table, query, mode, kanjiGrids(table)), query)
+	} else if isKanaQuery(query) {
+		var results ResultEntries
+		for _, spelling := range kanaSpellings(query, options) {
+			results = mergeResults(results, sortKanaResults(table, japaneseMatchCandidates(table, spelling, mode, kanaGrids(table)), spelling))
+		}
+		return results
+	}
+	var english_results, romaji_results ResultEntries
+	if options.Script != ScriptRomaji {
+		english_results = sortEngResults(table, englishMatchCandidates(table, strings.Fields(strings.ToLower(query)), mode), query)
+	}
+	if hiragana, katakana, ok := kana.FromRomaji(query); ok && options.Script != ScriptEnglish {
+		for _, spelling := range []string{hiragana, katakana} {
+			raw_results := japaneseMatchCandidates(table, spelling, mode, kanaGrids(table))
+			romaji_results = mergeResults(romaji_results, sortKanaResults(table, raw_results, spelling))
+		}
+	}
+	return mergeResults(english_results, romaji_results)
+}
+
+func japaneseMatchCandidates(table env.Environment, word string, mode MatchMode, grids japaneseGrids) searchgrids.EntryList {
+	characters := []rune(word)
+	var candidates searchgrids.EntryList
+	switch mode {
+	case MatchPrefix, MatchExact:
+		candidates = patternCandidates(table, characters, grids.forward)
+	case MatchSuffix:
+		if grids.backward == nil {
+			candidates = containsCandidates(table, characters, grids.forward)
+			break
+		}
+		candidates = patternCandidates(table, []rune(searchgrids.Reverse(word)), *grids.backward)
+	case MatchContains:
+		candidates = containsCandidates(table, characters, grids.forward)
+	}
+	pattern := modePattern(word, mode)
+	return filterHashes(candidates, func(wordID int, index uint16) bool {
+		return matchPattern(pattern, []rune(grids.text(wordID, index)))
+	})
+}
+
+// containsCandidates tries every position at which the query could start inside a word, intersecting the lists of its characters at the matching positions
+func containsCandidates(table env.Environment, characters []rune, grid patternGrid) searchgrids.EntryList {
+	anchor := -1
+	for i, letter := range characters {
+		if grid.positions(letter) != 0 {
+			anchor = i
+			break
+		}
+	}
+	if anchor == -1 {
+		return patternCandidates(table, []rune{anyRun}, grid)
+	}
+	var candidates searchgrids.EntryList
+	for position := anchor; position < grid.positions(characters[anchor]); position++ {
+		start := position - anchor
+		found := grid.list(characters[anchor], position).Decode()
+		for i := anchor + 1; i < len(characters) && len(found) != 0; i++ {
+			if grid.positions(characters[i]) != 0 {
+				found = mergeEntryListsFirstWord(found, grid.list(characters[i], start+i))
+			}
+		}
+		candidates = unionEntryLists(candidates, found)
+	}
+	return candidates
+}
+
+// For English the mode applies to the query as a run of whole words: a suffix query may start in the middle of the first word,
+// a prefix query may end in the middle of the last one, and the words in between have to match exactly. An exact query has to be the whole gloss,
+// its notes in parentheses and the "to" of verbs aside as engTier does, so that "=water" finds "water (esp. cool or cold)" and "=eat" finds "to eat"
+func englishMatchCandidates(table env.Environment, words []string, mode MatchMode) searchgrids.EntryList {
+	if len(words) == 0 {
+		return nil
+	}
+	forward := patternGrid{
+		list: func(letter rune, position int) searchgrids.PostingList {
+			return engEntryList(*table.English, letter, position)
+		},
+		positions: engPositionCount(*table.English),
+	}
+	patterns := make([]string, len(words))
+	copy(patterns, words)
+	if mode == MatchSuffix || mode == MatchContains {
+		patterns[0] = "*" + patterns[0]
+	}
+	if mode == MatchPrefix || mode == MatchContains {
+		patterns[len(patterns)-1] += "*"
+	}
+	var candidates searchgrids.EntryList
+	switch mode {
+	case MatchPrefix, MatchExact:
+		candidates = patternCandidates(table, []rune(words[0]), forward)
+	case MatchSuffix:
+		if table.EnglishSuffix == nil {
+			candidates = containsCandidates(table, []rune(words[0]), forward)
+			break
+		}
+		backward := patternGrid{
+			list: func(letter rune, position int) searchgrids.PostingList {
+				return engEntryList(*table.EnglishSuffix, letter, position)
+			},
+			positions: engPositionCount(*table.EnglishSuffix),
+		}
+		candidates = patternCandidates(table, []rune(searchgrids.Reverse(words[0])), backward)
+	case MatchContains:
+		candidates = containsCandidates(table, []rune(words[0]), forward)
+	}
+	query := glossKey(strings.Join(words, " "))
+	return filterHashes(candidates, func(wordID int, hash uint16) bool {
+		if mode == MatchExact {
+			return glossKey(glossContent(table, wordID, hash)) == query
+		}
+		return glossWordsMatch(table, wordID, hash, patterns)
+	})
+}
+
+func glossContent(table env.Environment, wordID int, hash uint16) string {
+	gloss := int(hash/100) % 20
+	sense := int(hash / 2000)
+	return table.Dict.Entries[wordID].Sense[sense-1].Glossary[gloss-1].Content
+}
+
+// glossWordsMatch checks the patterns against the gloss words starting at the one the hash points to
+func glossWordsMatch(table env.Environment, wordID int, hash uint16, patterns []string) bool {
+	word := int(hash % 100)
+	gloss := int(hash/100) % 20
+	sense := int(hash / 2000)
+	glosses := table.Dict.Entries[wordID].Sense[sense-1].Glossary
+	parsed := searchgrids.ParseWords(glosses[gloss-1].Content)
+	if word+len(patterns) > len(parsed) {
+		return false
+	}
+	for i, pattern := range patterns {
+		if !matchPattern([]rune(pattern), []rune(parsed[word+i])) {
+			return false
+		}
+	}
+	return true
+}
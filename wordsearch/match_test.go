@@ -0,0 +1,78 @@
+package wordsearch
+
+import "testing"
+
+func TestParseMatchMode(t *testing.T) {
+	for name, mode := range map[string]MatchMode{"": MatchPrefix, "prefix": MatchPrefix, "exact": MatchExact, "suffix": MatchSuffix, "contains": MatchContains} {
+		if parsed, err := ParseMatchMode(name); err != nil || parsed != mode {
+			t.Errorf("ParseMatchMode(%q) = %v, %v, expected %v", name, parsed, err, mode)
+		}
+	}
+	if _, err := ParseMatchMode("infix"); err == nil {
+		t.Errorf("ParseMatchMode accepts an unknown mode")
+	}
+}
+
+func TestMatchMarkers(t *testing.T) {
+	for _, test := range []struct {
+		query, word string
+		mode        MatchMode
+	}{
+		{"=水", "水", MatchExact},
+		{"= water ", "water", MatchExact},
+		{"*学", "学", MatchSuffix},
+		{"*学*", "学", MatchContains},
+		{"学*", "学", MatchPrefix},
+		{"学", "学", MatchContains}, // The mode given in the options is kept when the query has no marker
+		{"た?る", "た?る", MatchContains},
+		{"*た?る", "*た?る", MatchContains},
+		{"*", "*", MatchContains},
+	} {
+		if word, mode := matchMarkers(test.query, MatchContains); word != test.word || mode != test.mode {
+			t.Errorf("matchMarkers(%q) = %q, %v, expected %q, %v", test.query, word, mode, test.word, test.mode)
+		}
+	}
+}
+
+func TestMatchModes(t *testing.T) {
+	table := testTable()
+	for _, test := range []struct {
+		query    string
+		mode     MatchMode
+		readings []string
+	}{
+		// English, an exact query ignoring the notes in parentheses and the "to" of verbs like engTier does
+		{"water", MatchExact, []string{"みず"}},
+		{"eat", MatchExact, []string{"たべる", "くう"}},
+		{"to eat", MatchExact, []string{"たべる", "くう"}},
+		{"water supply", MatchExact, []string{"すいどう"}},
+		{"supply", MatchExact, nil},
+		{"water", MatchPrefix, []string{"みず", "すいどう"}},
+		{"ater", MatchSuffix, []string{"みず", "すいどう"}},
+		{"upply", MatchSuffix, []string{"すいどう"}},
+		{"nivers", MatchContains, []string{"だいがく"}},
+		// Kana
+		{"みず", MatchExact, []string{"みず"}},
+		{"がく", MatchExact, nil},
+		{"がく", MatchSuffix, []string{"だいがく"}},
+		{"いが", MatchContains, []string{"だいがく"}},
+		// Kanji
+		{"水", MatchExact, []string{"みず"}},
+		{"水", MatchPrefix, []string{"みず", "すいどう"}},
+		{"学", MatchSuffix, []string{"だいがく"}},
+		{"学", MatchContains, []string{"だいがく", "がくせい"}},
+		{"道", MatchExact, nil},
+	} {
+		results := matchResults(table, test.query, test.mode, Options{Script: ScriptEnglish})
+		if readings := found(table, results); !sameSet(readings, test.readings) {
+			t.Errorf("%q in mode %v found %v, expected %v", test.query, test.mode, readings, test.readings)
+		}
+	}
+	// The markers reach matchResults through Search
+	if readings := found(table, Search(table, "=water", Options{})); !sameStrings(readings, []string{"みず"}) {
+		t.Errorf("=water found %v, expected みず", readings)
+	}
+	if readings := found(table, Search(table, "*学", Options{})); !sameStrings(readings, []string{"だいがく"}) {
+		t.Errorf("*学 found %v, expected だいがく", readings)
+	}
+}
@@ -0,0 +1,110 @@
+package wordsearch
+
+// Proper names come from JMnedict, which has its own grids. By default they are only searched in full when no word matches the query,
+// since most lookups are for words and JMnedict is several times bigger than JMdict. Names written exactly like the query (田中, 東京) are
+// still looked up every time, and listed after the words of the same tier
+
+import (
+	"fmt"
+	"japp/env"
+	"strings"
+)
+
+// Dictionary selects which dictionaries a search goes through
+type Dictionary int
+
+const (
+	DictionaryAuto  Dictionary = iota // Words and the names that are exactly the query, or every matching name if no word matches
+	DictionaryWords                   // Words only
+	DictionaryNames                   // Names only
+)
+
+// ParseDictionary reads the name of a dictionary selection: auto, words or names
+func ParseDictionary(name string) (Dictionary, error) {
+	switch name {
+	case "", "auto":
+		return DictionaryAuto, nil
+	case "words":
+		return DictionaryWords, nil
+	case "names":
+		return DictionaryNames, nil
+	}
+	return DictionaryAuto, fmt.Errorf("unknown dictionary %q, expected auto, words or names", name)
+}
+
+// The name types of JMnedict are matched by keywords, which fit both the entity names (surname, fem, place) and their descriptions (family or surname, place name)
+var nameTypeKeywords = map[string][]string{
+	"surname": {"surname"},
+	"given":   {"given", "fem", "masc"},
+	"place":   {"place", "station"},
+	"company": {"company", "organization"},
+	"person":  {"person"},
+	"product": {"product"},
+}
+
+// ParseNameType checks the name type filter, which is one of surname, given, place, company, person, product or empty for all names
+func ParseNameType(name string) (string, error) {
+	if _, found := nameTypeKeywords[name]; name != "" && !found {
+		return "", fmt.Errorf("unknown name type %q, expected surname, given, place, company, person or product", name)
+	}
+	return name, nil
+}
+
+func nameResults(table env.Environment, query string, options Options) ResultEntries {
+	if table.Names == nil {
+		return nil
+	}
+	names := table.NamesEnvironment()
+	var results ResultEntries
+	for _, result := range wordResults(names, query, options) {
+		if options.NameType != "" && !hasNameType(names, result.Entry.WordID, options.NameType) {
+			continue
+		}
+		result.Name = true
+		results = append(results, result)
+	}
+	return results
+}
+
+// exactNameResults finds the names whose headword is the query, which can't be told apart from a word by the query alone
+// Patterns and suffix or contains searches are left out, since a name matching them is rarely what was looked for
+func exactNameResults(table env.Environment, query string, options Options) ResultEntries {
+	if _, mode := matchMarkers(query, options.Match); (mode != MatchPrefix && mode != MatchExact) || isPattern(query) {
+		return nil
+	}
+	options.Match = MatchExact
+	var results ResultEntries
+	for _, result := range nameResults(table, query, options) {
+		if result.Tier == TierExactHeadword {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// insertNames puts every name after the words ranked in the same or a higher tier, the names being sorted like the words
+func insertNames(words, names ResultEntries) ResultEntries {
+	var merged ResultEntries
+	i := 0
+	for _, name := range names {
+		for i < len(words) && words[i].Tier >= name.Tier {
+			merged = append(merged, words[i])
+			i++
+		}
+		merged = append(merged, name)
+	}
+	return append(merged, words[i:]...)
+}
+
+func hasNameType(names env.Environment, wordID int, filter string) bool {
+	for _, sense := range names.Dict.Entries[wordID].Sense {
+		for _, nameType := range sense.Misc {
+			for _, keyword := range nameTypeKeywords[filter] {
+				if strings.Contains(strings.ToLower(nameType), keyword) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package wordsearch
+
+// Pattern queries use '?' for any single character and '*' for any run of characters, e.g. "た?る", "*ごう", "?日" or "w?t*r"
+// A pattern has to match the whole reading, kanji form or English word, so "た?る" only finds three character readings
+// Characters at a known distance from the start of the word are looked up in the position grids and intersected like a regular query,
+// and the few candidates left are checked against the full pattern afterwards
+
+import (
+	"japp/env"
+	"japp/searchgrids"
+	"strings"
+)
+
+const (
+	anyCharacter = '?'
+	anyRun       = '*'
+)
+
+var patternReplacer = strings.NewReplacer("？", "?", "＊", "*")
+
+// wildcardRemover leaves the characters of a pattern that the results are scored against
+var wildcardRemover = strings.NewReplacer("?", "", "*", "")
+
+func isPattern(query string) bool {
+	return strings.ContainsAny(query, "?*？＊")
+}
+
+// patternGrid gives the pattern search access to one of the position grids
+type patternGrid struct {
+	list      func(letter rune, position int) searchgrids.PostingList
+	positions func(letter rune) int // Number of positions the grid holds for the letter, 0 if the grid doesn't index it at all
+	forms     func(wordID int) int  // Number of readings or kanji forms of an entry, only needed for patterns made of wildcards
+}
+
+func patternResults(table env.Environment, query string, options Options) ResultEntries {
+	query = patternReplacer.Replace(strings.TrimSpace(query))
+	var hasKana, hasKanji bool
+	for _, character := range query {
+		if searchgrids.IsKanji(character) {
+			hasKanji = true
+		} else if searchgrids.IsHiragana(character) || searchgrids.IsKatakana(character) {
+			hasKana = true
+		}
+	}
+	literal := wildcardRemover.Replace(query)
+	pattern := []rune(query)
+	if hasKanji {
+		grid := kanjiGrids(table).forward
+		raw_results := filterHashes(patternCandidates(table, pattern, grid), func(wordID int, index uint16) bool {
+			return matchPattern(pattern, []rune(table.KanjiForm(wordID, int(index))))
+		})
+		return sortKanjiResults(table, raw_results, literal)
+	} else if hasKana {
+		grid := kanaGrids(table).forward
+		var results ResultEntries
+		for _, spelling := range kanaSpellings(query, options) {
+			pattern := []rune(spelling)
+			raw_results := filterHashes(patternCandidates(table, pattern, grid), func(wordID int, index uint16) bool {
+				return matchPattern(pattern, []rune(table.Reading(wordID, int(index))))
+			})
+			results = mergeResults(results, sortKanaResults(table, raw_results, wildcardRemover.Replace(spelling)))
+		}
+		return results
+	}
+	raw_results := englishPatternCandidates(table, strings.Fields(strings.ToLower(query)))
+	return sortEngResults(table, raw_results, literal)
+}
+
+// patternCandidates narrows the dictionary down to the entries that may match the pattern
+func patternCandidates(table env.Environment, pattern []rune, grid patternGrid) searchgrids.EntryList {
+	var candidates searchgrids.EntryList
+	started := false
+	// Characters before the first '*' sit at a known position, so their lists can be intersected
+	for position, letter := range pattern {
+		if letter == anyRun {
+			break
+		}
+		if letter == anyCharacter || grid.positions(letter) == 0 {
+			continue // Characters the grid doesn't index (e.g. okurigana in a kanji pattern) are left to the final check
+		}
+		list := grid.list(letter, position)
+		if !started {
+			candidates = list.Decode()
+			started = true
+		} else {
+			candidates = mergeEntryListsFirstWord(candidates, list)
+		}
+	}
+	if started {
+		return candidates
+	}
+	// Otherwise we take every position of the first character that the grid knows about and let the final check do the rest
+	for _, letter := range pattern {
+		if letter == anyRun || letter == anyCharacter || grid.positions(letter) == 0 {
+			continue
+		}
+		for position := 0; position < grid.positions(letter); position++ {
+			candidates = unionEntryLists(candidates, grid.list(letter, position).Decode())
+		}
+		return candidates
+	}
+	// A pattern made only of wildcards has to be checked against every entry
+	if grid.forms == nil {
+		return nil
+	}
+	for wordID := range table.Dict.Entries {
+		var entry searchgrids.Entry
+		entry.WordID = wordID
+		for index := 0; index < grid.forms(wordID); index++ {
+			entry.Hash = append(entry.Hash, uint16(index))
+		}
+		if len(entry.Hash) != 0 {
+			candidates = append(candidates, entry)
+		}
+	}
+	return candidates
+}
+
+// English patterns are matched word by word against consecutive words of a gloss, the first word being looked up in the grid
+// They need at least one letter, since going through every word of every gloss would take far too long
+func englishPatternCandidates(table env.Environment, words []string) searchgrids.EntryList {
+	if len(words) == 0 {
+		return nil
+	}
+	grid := patternGrid{
+		list: func(letter rune, position int) searchgrids.PostingList {
+			return engEntryList(*table.English, letter, position)
+		},
+		positions: engPositionCount(*table.English),
+	}
+	return filterHashes(patternCandidates(table, []rune(words[0]), grid), func(wordID int, hash uint16) bool {
+		return glossWordsMatch(table, wordID, hash, words)
+	})
+}
+
+// filterHashes keeps the hashes for which keep returns true, and the entries that still have at least one of them
+func filterHashes(list searchgrids.EntryList, keep func(int, uint16) bool) searchgrids.EntryList {
+	var result searchgrids.EntryList
+	for _, entry := range list {
+		var appendix searchgrids.Entry
+		appendix.WordID = entry.WordID
+		appendix.Score = entry.Score
+		for _, hash := range entry.Hash {
+			if keep(entry.WordID, hash) {
+				appendix.Hash = append(appendix.Hash, hash)
+			}
+		}
+		if len(appendix.Hash) != 0 {
+			result = append(result, appendix)
+		}
+	}
+	return result
+}
+
+// unionEntryLists joins two lists sorted by WordID into one, merging the hashes of the entries found in both
+func unionEntryLists(first, second searchgrids.EntryList) searchgrids.EntryList {
+	var result searchgrids.EntryList
+	i, j := 0, 0
+	for i < len(first) || j < len(second) {
+		if j == len(second) || (i < len(first) && first[i].WordID < second[j].WordID) {
+			result = append(result, first[i])
+			i++
+		} else if i == len(first) || second[j].WordID < first[i].WordID {
+			result = append(result, second[j])
+			j++
+		} else {
+			merged := first[i]
+			merged.Hash = unionHashes(first[i].Hash, second[j].Hash)
+			result = append(result, merged)
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+func unionHashes(first, second searchgrids.Hash) searchgrids.Hash {
+	var result searchgrids.Hash
+	i, j := 0, 0
+	for i < len(first) || j < len(second) {
+		if j == len(second) || (i < len(first) && first[i] < second[j]) {
+			result = append(result, first[i])
+			i++
+		} else if i == len(first) || second[j] < first[i] {
+			result = append(result, second[j])
+			j++
+		} else {
+			result = append(result, first[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// matchPattern checks the whole text against a pattern with '?' and '*' wildcards, going back to the last '*' whenever a character doesn't fit
+func matchPattern(pattern, text []rune) bool {
+	p, t := 0, 0
+	star, mark := -1, 0
+	for t < len(text) {
+		if p < len(pattern) && (pattern[p] == anyCharacter || pattern[p] == text[t]) {
+			p++
+			t++
+		} else if p < len(pattern) && pattern[p] == anyRun {
+			star = p
+			mark = t
+			p++
+		} else if star != -1 {
+			p = star + 1
+			mark++
+			t = mark
+		} else {
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == anyRun {
+		p++
+	}
+	return p == len(pattern)
+}
+
+func engPositionCount(grid searchgrids.EngAlphabet) func(rune) int {
+	return func(letter rune) int {
+		return grid.PositionCount(int(letter) - 97)
+	}
+}
+
+func kanaPositionCount(grid searchgrids.KanaAlphabet) func(rune) int {
+	return func(letter rune) int {
+		var char int
+		if searchgrids.IsHiragana(letter) {
+			char = int(letter) - 12352
+		} else if searchgrids.IsKatakana(letter) {
+			char = int(letter) - 12448
+		} else {
+			return 0
+		}
+		return grid.PositionCount(char)
+	}
+}
+
+func kanjiPositionCount(grid searchgrids.KanjiAlphabet) func(rune) int {
+	return func(letter rune) int {
+		if !searchgrids.IsKanji(letter) {
+			return 0
+		}
+		return grid.PositionCount(int(letter))
+	}
+}
@@ -0,0 +1,78 @@
+package wordsearch
+
+// A result's score is the sum of a few signed components, so that nothing can wrap around and every part of a ranking can be printed and checked
+// The score only orders results that share the same tier and commonness (see tier.go)
+
+import (
+	"japp/env"
+	"japp/searchgrids"
+	"unicode/utf8"
+)
+
+// Weights of the penalties, in points per step
+const (
+	formWeight   = 10 // Every reading or kanji form listed before the matched one
+	senseWeight  = 10 // Every sense before the one holding the matched gloss
+	glossWeight  = 20 // Every gloss before the matched one within its sense
+	wordWeight   = 10 // Every word of the gloss before the first matched word
+	lengthWeight = 5  // Every character of the matched text that isn't covered by the query
+)
+
+// ScoreComponents is the breakdown of a result's score
+type ScoreComponents struct {
+	Entry  float64 // Score of the entry itself (priorities, number of forms and translations), see searchgrids.ScoreEntry
+	Form   float64 // Penalty for matching a reading, kanji form, sense, gloss or word that comes late in the entry
+	Length float64 // Penalty for the length of the matched text beyond the query
+}
+
+func (components ScoreComponents) Total() float64 {
+	return components.Entry + components.Form + components.Length
+}
+
+func calculateEngScore(table env.Environment, entry searchgrids.Entry, query string) ScoreComponents {
+	query_length := len(glossHeadword(query))
+	return bestComponents(entry, func(hash uint16) (float64, float64) {
+		word := int(hash % 100)
+		gloss := int(hash/100) % 20
+		sense := int(hash / 2000)
+		content := table.Dict.Entries[entry.WordID].Sense[sense-1].Glossary[gloss-1].Content
+		form := float64(-((sense-1)*senseWeight + (gloss-1)*glossWeight + word*wordWeight))
+		return form, lengthPenalty(len(glossHeadword(content)), query_length)
+	})
+}
+
+func calculateKanaScore(table env.Environment, entry searchgrids.Entry, query string) ScoreComponents {
+	query_length := utf8.RuneCountInString(query)
+	return bestComponents(entry, func(index uint16) (float64, float64) {
+		reading := table.Reading(entry.WordID, int(index)) // Normalized like the query, so that a decomposed が counts as one character
+		return float64(-int(index) * formWeight), lengthPenalty(utf8.RuneCountInString(reading), query_length)
+	})
+}
+
+func calculateKanjiScore(table env.Environment, entry searchgrids.Entry, query string) ScoreComponents {
+	query_length := utf8.RuneCountInString(query)
+	return bestComponents(entry, func(index uint16) (float64, float64) {
+		expression := table.KanjiForm(entry.WordID, int(index))
+		return float64(-int(index) * formWeight), lengthPenalty(utf8.RuneCountInString(expression), query_length)
+	})
+}
+
+// bestComponents scores every matched hash of the entry and keeps the one with the smallest penalties
+func bestComponents(entry searchgrids.Entry, penalties func(hash uint16) (form, length float64)) ScoreComponents {
+	components := ScoreComponents{Entry: float64(entry.Score)}
+	for i, hash := range entry.Hash {
+		form, length := penalties(hash)
+		if i == 0 || form+length > components.Form+components.Length {
+			components.Form, components.Length = form, length
+		}
+	}
+	return components
+}
+
+// Texts shorter than the query (a conjugated query matched against its dictionary form) aren't penalized
+func lengthPenalty(text_length, query_length int) float64 {
+	if text_length <= query_length {
+		return 0
+	}
+	return float64((query_length - text_length) * lengthWeight)
+}
@@ -0,0 +1,44 @@
+package wordsearch
+
+import (
+	"japp/searchgrids"
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// The length penalty compares the query with the forms as they are normalized, not as the dictionary writes them
+func TestLengthOfNormalizedForms(t *testing.T) {
+	table := newTable([]jmdict.JmdictEntry{
+		entry("\u304b\u3099", "\u304b\u3099", noun, "moth"), // が written with a combining dakuten
+		entry("蛾", "が", noun, "moth"),
+		entry("画家", "がか", noun, "painter"),
+	}, nil)
+	for _, test := range []struct {
+		wordID int
+		length float64
+	}{
+		{0, 0},
+		{1, 0},
+		{2, -lengthWeight},
+	} {
+		entry := searchgrids.Entry{WordID: test.wordID, Hash: searchgrids.Hash{0}}
+		if components := calculateKanaScore(table, entry, "が"); components.Length != test.length {
+			t.Errorf("the reading of %v gives the length penalty %v for が, expected %v", test.wordID, components.Length, test.length)
+		}
+	}
+	for _, test := range []struct {
+		wordID int
+		query  string
+		length float64
+	}{
+		{0, "が", 0},
+		{1, "蛾", 0},
+		{2, "画", -lengthWeight},
+	} {
+		entry := searchgrids.Entry{WordID: test.wordID, Hash: searchgrids.Hash{0}}
+		if components := calculateKanjiScore(table, entry, test.query); components.Length != test.length {
+			t.Errorf("the kanji form of %v gives the length penalty %v for %v, expected %v", test.wordID, components.Length, test.query, test.length)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package wordsearch
+
+// Results are ranked in tiers first, by how closely the matched headword, reading or gloss fits the query, so that "water" puts 水 above 水道
+// Inside a tier, common words (the news1, ichi1, spec1, spec2 and gai1 priorities used by JMdict) go before the rest, and only then the score decides
+
+import (
+	"japp/env"
+	"japp/searchgrids"
+	"regexp"
+	"strings"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// Tier is how closely a result matches the query, a higher tier ranking above a lower one regardless of the score
+type Tier int
+
+const (
+	TierNone          Tier = iota // The entry wasn't found by a search, e.g. it was looked up by its WordID
+	TierPartial                   // The query is found somewhere other than the start, e.g. in a later word of a gloss
+	TierPrefix                    // The headword, reading or gloss starts with the query
+	TierDeinflected               // The headword or reading is a dictionary form the query can be a conjugation of, see deinflectedResults
+	TierExactGloss                // One of the glosses is the query
+	TierExactReading              // One of the readings is the query
+	TierExactHeadword             // One of the kanji forms is the query, or one of the readings of a word written without kanji
+)
+
+var tierNames = map[Tier]string{
+	TierNone:          "",
+	TierPartial:       "partial",
+	TierPrefix:        "prefix",
+	TierDeinflected:   "deinflected",
+	TierExactGloss:    "exact gloss",
+	TierExactReading:  "exact reading",
+	TierExactHeadword: "exact headword",
+}
+
+func (tier Tier) String() string {
+	return tierNames[tier]
+}
+
+// ranksAbove tells whether the first result has to be listed before the second one
+func ranksAbove(first, second ResultEntry) bool {
+	if first.Tier != second.Tier {
+		return first.Tier > second.Tier
+	}
+	if first.Common != second.Common {
+		return first.Common
+	}
+	return first.Score > second.Score
+}
+
+func isCommon(entry jmdict.JmdictEntry) bool {
+	for _, kanji := range entry.Kanji {
+		if hasCommonPriority(kanji.Priorities) {
+			return true
+		}
+	}
+	for _, reading := range entry.Readings {
+		if hasCommonPriority(reading.Priorities) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCommonPriority(priorities []string) bool {
+	for _, priority := range priorities {
+		switch priority {
+		case "news1", "ichi1", "spec1", "spec2", "gai1":
+			return true
+		}
+	}
+	return false
+}
+
+func kanjiTier(table env.Environment, wordID int, hashes searchgrids.Hash, query string) Tier {
+	entry := table.Dict.Entries[wordID]
+	best := TierNone
+	for _, index := range hashes {
+		if int(index) >= len(entry.Kanji) {
+			continue
+		}
+		best = betterTier(best, textTier(table.KanjiForm(wordID, int(index)), query, TierExactHeadword))
+	}
+	return best
+}
+
+func kanaTier(table env.Environment, wordID int, hashes searchgrids.Hash, query string) Tier {
+	entry := table.Dict.Entries[wordID]
+	exact := TierExactReading
+	if len(entry.Kanji) == 0 {
+		exact = TierExactHeadword // Words usually written in kana have their reading as the headword
+	}
+	best := TierNone
+	for _, index := range hashes {
+		if int(index) >= len(entry.Readings) {
+			continue
+		}
+		best = betterTier(best, textTier(table.Reading(wordID, int(index)), query, exact))
+	}
+	return best
+}
+
+func engTier(entry jmdict.JmdictEntry, hashes searchgrids.Hash, query string) Tier {
+	query = glossKey(query)
+	best := TierNone
+	for _, hash := range hashes {
+		word := hash % 100
+		gloss := int(hash/100) % 20
+		sense := int(hash / 2000)
+		if sense < 1 || sense > len(entry.Sense) || gloss < 1 || gloss > len(entry.Sense[sense-1].Glossary) {
+			continue
+		}
+		content := entry.Sense[sense-1].Glossary[gloss-1].Content
+		if glossKey(content) == query {
+			best = betterTier(best, TierExactGloss)
+		} else if word == 0 {
+			best = betterTier(best, TierPrefix)
+		} else {
+			best = betterTier(best, TierPartial)
+		}
+	}
+	return best
+}
+
+func textTier(text, query string, exact Tier) Tier {
+	if text == query {
+		return exact
+	} else if strings.HasPrefix(text, query) {
+		return TierPrefix
+	}
+	return TierPartial
+}
+
+func betterTier(first, second Tier) Tier {
+	if second > first {
+		return second
+	}
+	return first
+}
+
+var glossNotes = regexp.MustCompile(`\([^)]*\)`)
+
+// glossHeadword drops the notes in parentheses and the "to" of verbs, so that "to eat" and "water (esp. cool or cold)" are exact matches for "eat" and "water"
+func glossHeadword(content string) string {
+	content = strings.TrimSpace(glossNotes.ReplaceAllString(content, ""))
+	if strings.HasPrefix(strings.ToLower(content), "to ") {
+		content = content[3:]
+	}
+	return content
+}
+
+// glossKey is what a gloss is compared with a query by for an exact match, the query going through it as well
+func glossKey(text string) string {
+	return strings.Join(glossWords(glossHeadword(text)), " ")
+}
+
+func glossWords(text string) []string {
+	var words []string
+	for _, word := range searchgrids.ParseWords(text) {
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
@@ -0,0 +1,106 @@
+package wordsearch
+
+import (
+	"japp/searchgrids"
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+func TestRanksAbove(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		first, second ResultEntry
+		above         bool
+	}{
+		{"higher tier", ResultEntry{Tier: TierExactHeadword}, ResultEntry{Tier: TierExactReading, Common: true, Score: 100}, true},
+		{"lower tier", ResultEntry{Tier: TierPrefix, Common: true, Score: 100}, ResultEntry{Tier: TierDeinflected}, false},
+		{"deinflected below exact gloss", ResultEntry{Tier: TierDeinflected}, ResultEntry{Tier: TierExactGloss}, false},
+		{"deinflected above prefix", ResultEntry{Tier: TierDeinflected}, ResultEntry{Tier: TierPrefix, Common: true}, true},
+		{"common first", ResultEntry{Tier: TierPrefix, Common: true, Score: 1}, ResultEntry{Tier: TierPrefix, Score: 100}, true},
+		{"rare second", ResultEntry{Tier: TierPrefix, Score: 100}, ResultEntry{Tier: TierPrefix, Common: true, Score: 1}, false},
+		{"higher score", ResultEntry{Tier: TierPrefix, Score: 20}, ResultEntry{Tier: TierPrefix, Score: 10}, true},
+		{"equal", ResultEntry{Tier: TierPrefix, Score: 10}, ResultEntry{Tier: TierPrefix, Score: 10}, false},
+	} {
+		if above := ranksAbove(test.first, test.second); above != test.above {
+			t.Errorf("%v: ranksAbove = %v, expected %v", test.name, above, test.above)
+		}
+	}
+}
+
+func TestJapaneseTiers(t *testing.T) {
+	table := newTable([]jmdict.JmdictEntry{
+		entry("水道", "すいどう", noun, "water supply"),
+		entry("", "スゴイ", adjective, "amazing"),
+		entry("時々", "ときどき", noun, "sometimes"),
+	}, nil)
+	all := searchgrids.Hash{0}
+	for _, test := range []struct {
+		name   string
+		tier   Tier
+		wanted Tier
+	}{
+		{"kanji exact", kanjiTier(table, 0, all, "水道"), TierExactHeadword},
+		{"kanji prefix", kanjiTier(table, 0, all, "水"), TierPrefix},
+		{"kanji partial", kanjiTier(table, 0, all, "道"), TierPartial},
+		{"kanji normalized", kanjiTier(table, 2, all, "時時"), TierExactHeadword},
+		{"kanji missing form", kanjiTier(table, 0, searchgrids.Hash{3}, "水道"), TierNone},
+		{"reading of a kanji word", kanaTier(table, 0, all, "すいどう"), TierExactReading},
+		{"reading of a kana word", kanaTier(table, 1, all, "スゴイ"), TierExactHeadword},
+		{"reading prefix", kanaTier(table, 0, all, "すい"), TierPrefix},
+		{"reading partial", kanaTier(table, 0, all, "どう"), TierPartial},
+	} {
+		if test.tier != test.wanted {
+			t.Errorf("%v: tier %v, expected %v", test.name, test.tier, test.wanted)
+		}
+	}
+}
+
+func TestEngTier(t *testing.T) {
+	word := entry("食べる", "たべる", ichidan, "to eat", "to live on (e.g. a salary)")
+	word.Sense = append(word.Sense, jmdict.JmdictSense{Glossary: []jmdict.JmdictGlossary{{Content: "water (esp. cool or cold)"}}})
+	// A hash is the word of the gloss, plus 100 times the gloss and 2000 times the sense, both counted from 1
+	hash := func(sense, gloss, word int) searchgrids.Hash {
+		return searchgrids.Hash{uint16(2000*sense + 100*gloss + word)}
+	}
+	for _, test := range []struct {
+		query  string
+		hashes searchgrids.Hash
+		tier   Tier
+	}{
+		{"eat", hash(1, 1, 1), TierExactGloss},
+		{"to eat", hash(1, 1, 0), TierExactGloss},
+		{"live on", hash(1, 2, 1), TierExactGloss},
+		{"water", hash(2, 1, 0), TierExactGloss},
+		{"wat", hash(2, 1, 0), TierPrefix},
+		{"cool", hash(2, 1, 2), TierPartial},
+		{"eat", hash(3, 1, 0), TierNone},
+	} {
+		if tier := engTier(word, test.hashes, test.query); tier != test.tier {
+			t.Errorf("%q at %v: tier %v, expected %v", test.query, test.hashes, tier, test.tier)
+		}
+	}
+}
+
+// Deinflected results are labelled with their own tier, below the exact matches of the query
+func TestDeinflectedTier(t *testing.T) {
+	table := newTable([]jmdict.JmdictEntry{
+		entry("高い", "たかい", adjective, "high"),
+		entry("高さ", "たかさ", noun, "height"),
+		entry("", "する", "suru verb - included", "to do"),
+	}, nil)
+	for _, test := range []struct{ query, reading string }{{"高さ", "たかい"}, {"した", "する"}} {
+		tier := TierNone
+		for _, result := range Search(table, test.query, Options{}) {
+			if found(table, ResultEntries{result})[0] == test.reading {
+				tier = result.Tier
+			}
+		}
+		if tier != TierDeinflected {
+			t.Errorf("%v gives %v the tier %v, expected %v", test.query, test.reading, tier, TierDeinflected)
+		}
+	}
+	if results := Search(table, "高さ", Options{}); len(results) != 2 || results[0].Tier != TierExactHeadword {
+		t.Errorf("高さ isn't an exact headword for its own query")
+	}
+}
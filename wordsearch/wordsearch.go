@@ -1,38 +1,333 @@
 package wordsearch
 
 import (
+	"fmt"
+	"japp/deinflect"
 	"japp/env"
+	"japp/kana"
+	"japp/normalize"
 	"japp/searchgrids"
-	"regexp"
 	"strings"
+
+	"foosoft.net/projects/jmdict"
 )
 
 type ResultEntry struct {
-	Entry searchgrids.Entry
-	Score uint16
+	Entry      searchgrids.Entry
+	Score      float64         // Total of the score components, which orders results of the same tier
+	Components ScoreComponents // What the score is made of, printed by the debug format
+	Inflection []string        // Inflections that turn the matched dictionary form into the query, starting from the dictionary form
+	Tier       Tier            // How closely the entry matches the query, see tier.go
+	Common     bool            // Whether the entry is marked as a common word in JMdict
+	Name       bool            // The WordID points into the names dictionary (env.Environment.Names) instead of JMdict
 }
 
 type ResultEntries []ResultEntry
 
+// Script decides how a query typed in Latin letters is interpreted
+type Script int
+
+const (
+	ScriptAuto    Script = iota // English glosses, merged with kana readings if the query is valid romaji
+	ScriptEnglish               // English glosses only
+	ScriptRomaji                // Kana readings only, the query is transliterated from romaji
+)
+
+// ParseScript reads the name of a script as it is given on the command line or in a request: auto, en or romaji
+func ParseScript(name string) (Script, error) {
+	switch name {
+	case "", "auto":
+		return ScriptAuto, nil
+	case "en":
+		return ScriptEnglish, nil
+	case "romaji":
+		return ScriptRomaji, nil
+	}
+	return ScriptAuto, fmt.Errorf("unknown script %q, expected auto, en or romaji", name)
+}
+
+// Options holds the settings of a single search, the zero value being the default behaviour of SearchQuery
+type Options struct {
+	Script     Script
+	Match      MatchMode // Part of the word the query has to match, which can also be given in the query itself (see matchMarkers)
+	Dictionary Dictionary
+	NameType   string // Only keeps the names of this type (see ParseNameType), all of them if empty
+	FoldKana   bool   // Kana queries also match readings written in the other script, e.g. ネコ finds ねこ, see kanaSpellings
+	Offset     int    // Number of sorted results to skip, used by SearchPage
+	Limit      int    // Maximum number of results returned by SearchPage, 0 meaning all of them
+	Examples   int    // Number of example sentences shown under every result, DefaultExamples if 0 and none if NoExamples
+}
+
+// DefaultLimit is the number of results shown at once when the user hasn't asked for another page size
+const DefaultLimit = 10
+
+const (
+	DefaultExamples = 2  // Example sentences shown under every result when the options don't say otherwise
+	NoExamples      = -1 // Hides the example sentences
+)
+
+// Page is a part of the sorted results of a search, together with the number of results that were found in total
+type Page struct {
+	Results  ResultEntries
+	Total    int
+	Offset   int
+	Limit    int
+	Examples int // Taken from the options of the search, see ExampleCount
+}
+
 func SearchQuery(table env.Environment, query string) ResultEntries {
+	return Search(table, query, Options{})
+}
+
+// SearchPage runs the search and only returns the results selected by the offset and limit of the options
+func SearchPage(table env.Environment, query string, options Options) Page {
+	page := Paginate(Search(table, query, options), options.Offset, options.Limit)
+	page.Examples = options.Examples
+	return page
+}
+
+// Paginate cuts a page out of a list of results, a limit of 0 taking everything after the offset
+func Paginate(results ResultEntries, offset, limit int) Page {
+	page := Page{Total: len(results), Offset: offset, Limit: limit}
+	if offset < 0 {
+		page.Offset = 0
+	}
+	if page.Offset >= len(results) {
+		return page
+	}
+	page.Results = results[page.Offset:]
+	if limit > 0 && len(page.Results) > limit {
+		page.Results = page.Results[:limit]
+	}
+	return page
+}
+
+// HasNext reports whether there are results after this page
+func (page Page) HasNext() bool {
+	return page.Offset+len(page.Results) < page.Total
+}
+
+// ExampleCount is the number of example sentences to show under every result of the page
+func (page Page) ExampleCount() int {
+	if page.Examples == 0 {
+		return DefaultExamples
+	} else if page.Examples < 0 {
+		return 0
+	}
+	return page.Examples
+}
+
+// HasPrevious reports whether there are results before this page
+func (page Page) HasPrevious() bool {
+	return page.Offset > 0
+}
+
+func Search(table env.Environment, query string, options Options) ResultEntries {
+	query = normalize.String(query) // The grids hold normalized words, see the normalize package
+	if options.Dictionary == DictionaryNames {
+		return nameResults(table, query, options)
+	}
+	results := wordResults(table, query, options)
+	if options.Dictionary == DictionaryAuto {
+		if len(results) == 0 {
+			return nameResults(table, query, options)
+		}
+		results = insertNames(results, exactNameResults(table, query, options))
+	}
+	return results
+}
+
+func wordResults(table env.Environment, query string, options Options) ResultEntries {
 	var words []string
 	var search_results ResultEntries
-	if isKanaQuery(query) {
-		words = parseKana(query)
-		raw_results := kanaResults(table.Kana, words)
-		search_results = sortKanaResults(table, raw_results, query)
+	query, mode := matchMarkers(query, options.Match)
+	if mode != MatchPrefix {
+		search_results = matchResults(table, query, mode, options)
+	} else if isPattern(query) {
+		search_results = patternResults(table, query, options)
 	} else if isKanjiQuery(query) {
 		words = parseKanji(query)
 		raw_results := kanjiResults(table.Kanji, words)
+		raw_results = matchKanjiForms(table, raw_results, words)
 		search_results = sortKanjiResults(table, raw_results, query)
+		search_results = mergeResults(search_results, deinflectedResults(table, query))
+	} else if isKanaQuery(query) {
+		var deinflected_results ResultEntries
+		for _, spelling := range kanaSpellings(query, options) {
+			raw_results := kanaResults(table.Kana, parseKana(spelling))
+			search_results = mergeResults(search_results, sortKanaResults(table, raw_results, spelling))
+			deinflected_results = mergeResults(deinflected_results, deinflectedResults(table, spelling))
+		}
+		search_results = mergeResults(search_results, deinflected_results)
+		if options.FoldKana {
+			search_results = prependResults(search_results, longVowelResults(table, query))
+		}
 	} else {
-		words = searchgrids.ParseWords(query)
-		raw_results := engResults(table.English, words)
-		search_results = sortEngResults(table, raw_results, query)
+		var english_results, romaji_results ResultEntries
+		if options.Script != ScriptRomaji {
+			words = searchgrids.ParseWords(query)
+			raw_results := engResults(table.English, words)
+			english_results = sortEngResults(table, raw_results, query)
+		}
+		if options.Script != ScriptEnglish {
+			romaji_results = romajiResults(table, query)
+		}
+		search_results = mergeResults(english_results, romaji_results)
 	}
 	return search_results
 }
 
+// Romaji queries are transliterated into both kana scripts, since long vowels are spelled differently in hiragana (こうひい) and katakana (コーヒー)
+func romajiResults(table env.Environment, query string) ResultEntries {
+	var results ResultEntries
+	hiragana, katakana, ok := kana.FromRomaji(query)
+	if !ok {
+		return results
+	}
+	for i, spelling := range []string{hiragana, katakana} {
+		if i == 1 && spelling == kana.HiraganaToKatakana(hiragana) {
+			break // Both scripts share the kana grid, so without long vowels the katakana spelling would only repeat the same search
+		}
+		raw_results := kanaResults(table.Kana, parseKana(spelling))
+		results = mergeResults(results, sortKanaResults(table, raw_results, spelling))
+	}
+	return results
+}
+
+// kanaSpellings gives the spellings a kana query is searched with: the query itself and, when the scripts are folded, its hiragana and katakana forms
+// The kana grid already shares its slots between the two scripts, but the readings a result is ranked and filtered by are compared as written,
+// so searching every spelling is what lets ネコ find ねこ as an exact reading and すごい find スゴイ
+func kanaSpellings(query string, options Options) []string {
+	spellings := []string{query}
+	if !options.FoldKana {
+		return spellings
+	}
+	for _, spelling := range []string{normalize.FoldKana(query), kana.HiraganaToKatakana(query)} {
+		if spelling != spellings[len(spellings)-1] && spelling != query {
+			spellings = append(spellings, spelling)
+		}
+	}
+	return spellings
+}
+
+// longVowelResults finds the readings that only match a kana query once long vowels are written out on both sides, as コーヒー and こうひい do
+// The grid is looked up with a wildcard in place of every kana of the query that could stand for a ー, kanaResults skipping it like any character
+// it doesn't index, and the candidates are compared with the query after both went through kana.ExpandLongVowels. Different words can match this way (ボート and ぼうと), so these results are only listed
+// after the ones matching the query as written, and keep the tier the query as written gives them
+func longVowelResults(table env.Environment, query string) ResultEntries {
+	folded := kana.ExpandLongVowels(normalize.FoldKana(query))
+	characters := []rune(folded)
+	lookup := append([]rune{}, characters...)
+	for i := 1; i < len(characters); i++ {
+		previous := string(characters[i-1])
+		if kana.ExpandLongVowels(previous+"ー") == previous+string(characters[i]) {
+			lookup[i] = '?'
+		}
+	}
+	raw_results := filterHashes(kanaResults(table.Kana, parseKana(string(lookup))), func(wordID int, index uint16) bool {
+		return strings.HasPrefix(kana.ExpandLongVowels(kana.KatakanaToHiragana(table.Reading(wordID, int(index)))), folded)
+	})
+	return sortKanaResults(table, raw_results, query)
+}
+
+// Conjugated queries (食べました, 高くない) are turned back into candidate dictionary forms, which only count as results if an entry has exactly that spelling
+// and one of its senses has a part of speech that can be conjugated that way
+// A deinflection is only a guess (いろ could be the imperative of いる), so the results are ranked with the other results instead of ahead of them,
+// in a tier of their own below the exact matches of the query: a word spelled exactly as the query comes first, and 高い isn't an exact match for 高さ
+func deinflectedResults(table env.Environment, query string) ResultEntries {
+	var results ResultEntries
+	for _, candidate := range deinflect.Deinflect(query) {
+		var raw_results searchgrids.EntryList
+		var candidate_results ResultEntries
+		if isKanjiQuery(candidate.Word) {
+			candidate_words := parseKanji(candidate.Word)
+			raw_results = kanjiResults(table.Kanji, candidate_words)
+			raw_results = matchKanjiForms(table, raw_results, candidate_words)
+			candidate_results = sortKanjiResults(table, raw_results, candidate.Word)
+		} else {
+			raw_results = kanaResults(table.Kana, parseKana(candidate.Word))
+			candidate_results = sortKanaResults(table, raw_results, candidate.Word)
+		}
+		for _, result := range candidate_results {
+			entry := table.Dict.Entries[result.Entry.WordID]
+			if !spelledAs(table, result.Entry.WordID, result.Entry.Hash, candidate.Word) || !conjugatesAs(entry, candidate.Type) {
+				continue
+			}
+			if isKanjiQuery(candidate.Word) {
+				result.Tier = betterTier(kanjiTier(table, result.Entry.WordID, result.Entry.Hash, query), TierDeinflected)
+			} else {
+				result.Tier = betterTier(kanaTier(table, result.Entry.WordID, result.Entry.Hash, query), TierDeinflected)
+			}
+			result.Inflection = candidate.Reasons
+			results = mergeResults(results, ResultEntries{result})
+		}
+	}
+	return results
+}
+
+// The hashes of kana and kanji entries are the indexes of the matched readings or kanji forms
+func spelledAs(table env.Environment, wordID int, hashes searchgrids.Hash, word string) bool {
+	entry := table.Dict.Entries[wordID]
+	kanji := isKanjiQuery(word)
+	for _, index := range hashes {
+		if kanji && int(index) < len(entry.Kanji) && table.KanjiForm(wordID, int(index)) == word {
+			return true
+		} else if !kanji && int(index) < len(entry.Readings) && table.Reading(wordID, int(index)) == word {
+			return true
+		}
+	}
+	return false
+}
+
+func conjugatesAs(entry jmdict.JmdictEntry, class deinflect.Type) bool {
+	for _, sense := range entry.Sense {
+		for _, pos := range sense.PartsOfSpeech {
+			if deinflect.MatchesPartOfSpeech(class, pos) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prependResults puts the first list in front of the second one, dropping the entries of the second list that are already present
+func prependResults(first, second ResultEntries) ResultEntries {
+	if len(first) == 0 {
+		return second
+	}
+	seen := make(map[int]bool)
+	for _, result := range first {
+		seen[result.Entry.WordID] = true
+	}
+	results := append(ResultEntries{}, first...)
+	for _, result := range second {
+		if !seen[result.Entry.WordID] {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// mergeResults joins the results of two searches, keeping the better scored copy of the entries found by both of them
+func mergeResults(first, second ResultEntries) ResultEntries {
+	var merged ResultEntries
+	seen := make(map[int]int)
+	for _, list := range []ResultEntries{first, second} {
+		for _, result := range list {
+			if index, found := seen[result.Entry.WordID]; found {
+				if ranksAbove(result, merged[index]) {
+					merged[index] = result
+				}
+				continue
+			}
+			seen[result.Entry.WordID] = len(merged)
+			merged = append(merged, result)
+		}
+	}
+	quicksortResults(merged, 0, len(merged)-1)
+	return merged
+}
+
 func isKanaQuery(query string) bool {
 	for _, character := range query {
 		if searchgrids.IsHiragana(character) || searchgrids.IsKatakana(character) {
@@ -53,26 +348,25 @@ func isKanjiQuery(query string) bool {
 	return false
 }
 
+// Kana and kanji queries keep every character, since the ones the grid doesn't index still shift the position of the following ones (the ３ of ３日)
 func parseKana(query string) []string {
-	temp := regexp.MustCompile(`[^ぁ-ヾ ]+`).ReplaceAllString(query, "")
-	parsed_words := strings.Split(temp, " ")
+	parsed_words := strings.Split(query, " ")
 	return parsed_words
 }
 
 func parseKanji(query string) []string {
-	temp := regexp.MustCompile(`[^ぁ-ヾ一-龯㐀-䶿 ]+`).ReplaceAllString(query, "")
-	parsed_words := strings.Split(temp, " ")
+	parsed_words := strings.Split(query, " ")
 	return parsed_words
 }
 
 func engResults(english *searchgrids.EngAlphabet, words []string) searchgrids.EntryList {
 	var old searchgrids.EntryList
-	var new searchgrids.EntryList
+	var new searchgrids.PostingList
 	for substring, word := range words {
 		for position, letter := range word {
 			new = engEntryList(*english, letter, position)
 			if position == 0 && substring == 0 {
-				old = new
+				old = new.Decode()
 			} else if substring == 0 {
 				old = mergeEntryListsFirstWord(old, new)
 			} else {
@@ -85,13 +379,17 @@ func engResults(english *searchgrids.EngAlphabet, words []string) searchgrids.En
 
 func kanaResults(kana *searchgrids.KanaAlphabet, words []string) searchgrids.EntryList {
 	var old searchgrids.EntryList
-	var new searchgrids.EntryList
+	var new searchgrids.PostingList
+	started := false
 	for substring, word := range words {
-		for position, letter := range word {
-			pos := position / 3
+		for pos, letter := range []rune(word) {
+			if !searchgrids.IsHiragana(letter) && !searchgrids.IsKatakana(letter) {
+				continue // Not indexed, but it still takes up its position like in the grid
+			}
 			new = kanaEntryList(*kana, letter, pos)
-			if pos == 0 && substring == 0 {
-				old = new
+			if !started {
+				old = new.Decode()
+				started = true
 			} else if substring == 0 {
 				old = mergeEntryListsFirstWord(old, new)
 			} else {
@@ -104,16 +402,17 @@ func kanaResults(kana *searchgrids.KanaAlphabet, words []string) searchgrids.Ent
 
 func kanjiResults(kanji *searchgrids.KanjiAlphabet, words []string) searchgrids.EntryList {
 	var old searchgrids.EntryList
-	var new searchgrids.EntryList
+	var new searchgrids.PostingList
+	started := false
 	for substring, word := range words {
-		for position, letter := range word {
-			pos := position / 3
+		for pos, letter := range []rune(word) {
 			if !searchgrids.IsKanji(letter) {
-				continue
+				continue // Not indexed, but it still takes up its position like in the grid
 			}
 			new = kanjiEntryList(*kanji, letter, pos)
-			if pos == 0 && substring == 0 {
-				old = new
+			if !started {
+				old = new.Decode()
+				started = true
 			} else if substring == 0 {
 				old = mergeEntryListsFirstWord(old, new)
 			} else {
@@ -124,10 +423,36 @@ func kanjiResults(kanji *searchgrids.KanjiAlphabet, words []string) searchgrids.
 	return old
 }
 
+// The kanji grid only indexes kanji, so the okurigana and other characters of a mixed query (食べる, お茶, ３日) are checked against the kanji forms
+// of the candidates afterwards, keeping the forms that start with the first word of the query character for character
+// Every following word is checked the same way, against that form or the one after it like matchHash does, so that 食べる 食う doesn't find 食べる
+func matchKanjiForms(table env.Environment, raw_results searchgrids.EntryList, words []string) searchgrids.EntryList {
+	return filterHashes(raw_results, func(wordID int, index uint16) bool {
+		for substring, word := range words {
+			if !formStartsWith(table, wordID, int(index), word) && (substring == 0 || !formStartsWith(table, wordID, int(index)+1, word)) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func formStartsWith(table env.Environment, wordID, index int, word string) bool {
+	return index < len(table.Dict.Entries[wordID].Kanji) && strings.HasPrefix(table.KanjiForm(wordID, index), word)
+}
+
 func sortEngResults(table env.Environment, raw_results searchgrids.EntryList, query string) ResultEntries {
 	var results ResultEntries
 	for _, entry := range raw_results {
-		results = append(results, ResultEntry{entry, calculateEngScore(table, entry, query)})
+		dict_entry := table.Dict.Entries[entry.WordID]
+		result := ResultEntry{
+			Entry:      entry,
+			Components: calculateEngScore(table, entry, query),
+			Tier:       engTier(dict_entry, entry.Hash, query),
+			Common:     isCommon(dict_entry),
+		}
+		result.Score = result.Components.Total()
+		results = append(results, result)
 	}
 	quicksortResults(results, 0, len(results)-1)
 	return results
@@ -136,7 +461,15 @@ func sortEngResults(table env.Environment, raw_results searchgrids.EntryList, qu
 func sortKanaResults(table env.Environment, raw_results searchgrids.EntryList, query string) ResultEntries {
 	var results ResultEntries
 	for _, entry := range raw_results {
-		results = append(results, ResultEntry{entry, calculateKanaScore(table, entry, query)})
+		dict_entry := table.Dict.Entries[entry.WordID]
+		result := ResultEntry{
+			Entry:      entry,
+			Components: calculateKanaScore(table, entry, query),
+			Tier:       kanaTier(table, entry.WordID, entry.Hash, query),
+			Common:     isCommon(dict_entry),
+		}
+		result.Score = result.Components.Total()
+		results = append(results, result)
 	}
 	quicksortResults(results, 0, len(results)-1)
 	return results
@@ -145,81 +478,20 @@ func sortKanaResults(table env.Environment, raw_results searchgrids.EntryList, q
 func sortKanjiResults(table env.Environment, raw_results searchgrids.EntryList, query string) ResultEntries {
 	var results ResultEntries
 	for _, entry := range raw_results {
-		results = append(results, ResultEntry{entry, calculateKanjiScore(table, entry, query)})
+		dict_entry := table.Dict.Entries[entry.WordID]
+		result := ResultEntry{
+			Entry:      entry,
+			Components: calculateKanjiScore(table, entry, query),
+			Tier:       kanjiTier(table, entry.WordID, entry.Hash, query),
+			Common:     isCommon(dict_entry),
+		}
+		result.Score = result.Components.Total()
+		results = append(results, result)
 	}
 	quicksortResults(results, 0, len(results)-1)
 	return results
 }
 
-func calculateEngScore(table env.Environment, entry searchgrids.Entry, query string) uint16 {
-	var score uint16
-	var best uint16
-	var content_length uint16
-
-	query_length := uint16(len(query))
-	for i, hash := range entry.Hash {
-		score = 0
-		content := hash % 100
-		score += 2 - content
-		hash /= 100
-		gloss := hash % 20
-		score -= 2 * (gloss - 1)
-		hash /= 20
-		score -= (hash - 1)
-		content_length = uint16(len(table.Dict.Entries[entry.WordID].Sense[hash-1].Glossary[gloss-1].Content))
-		score += 10 - (content_length-query_length)*(content+1)
-		if i == 0 {
-			best = score
-		} else if score > best {
-			best = score
-		}
-	}
-	best = best * entry.Score
-	return best
-}
-
-func calculateKanaScore(table env.Environment, entry searchgrids.Entry, query string) uint16 {
-	var score uint16
-	var best uint16
-	var reading_length int
-
-	query_length := len(query)
-	for i, index := range entry.Hash {
-		score = 0
-		score += 3 - index
-		reading_length = len(table.Dict.Entries[entry.WordID].Readings[index].Reading)
-		score *= uint16(10 - (reading_length - query_length))
-		if i == 0 {
-			best = score
-		} else if score > best {
-			best = score
-		}
-	}
-	best = best * entry.Score
-	return best
-}
-
-func calculateKanjiScore(table env.Environment, entry searchgrids.Entry, query string) uint16 {
-	var score uint16
-	var best uint16
-	var kanji_length int
-
-	query_length := len(query)
-	for i, index := range entry.Hash {
-		score = 0
-		score += 3 - index
-		kanji_length = len(table.Dict.Entries[entry.WordID].Kanji[index].Expression)
-		score *= uint16(10 - (kanji_length - query_length))
-		if i == 0 {
-			best = score
-		} else if score > best {
-			best = score
-		}
-	}
-	best = best * entry.Score
-	return best
-}
-
 func quicksortResults(results ResultEntries, low, high int) {
 	if low >= high {
 		return
@@ -230,10 +502,10 @@ func quicksortResults(results ResultEntries, low, high int) {
 }
 
 func quicksortPartition(results ResultEntries, low, high int) int {
-	pivot := results[high].Score
+	pivot := results[high]
 	i := low - 1
 	for j := low; j < high; j++ {
-		if results[j].Score > pivot {
+		if ranksAbove(results[j], pivot) {
 			i++
 			results[i], results[j] = results[j], results[i]
 		}
@@ -243,90 +515,59 @@ func quicksortPartition(results ResultEntries, low, high int) int {
 }
 
 // This function will be used during search. It will pull up a list of words where (letter in position) is true
-func engEntryList(grid searchgrids.EngAlphabet, letter rune, position int) searchgrids.EntryList {
+func engEntryList(grid searchgrids.EngAlphabet, letter rune, position int) searchgrids.PostingList {
 	var char int = int(letter) - 97
-	return grid.Alphabet[char].Positions[position].List
+	return grid.Postings(char, position)
 }
 
-func kanaEntryList(grid searchgrids.KanaAlphabet, letter rune, position int) searchgrids.EntryList {
+func kanaEntryList(grid searchgrids.KanaAlphabet, letter rune, position int) searchgrids.PostingList {
 	var char int
 	if searchgrids.IsHiragana(letter) {
 		char = int(letter) - 12352
 	} else {
 		char = int(letter) - 12448
 	}
-	return grid.Alphabet[char].Positions[position].List
+	return grid.Postings(char, position)
 }
 
-func kanjiEntryList(grid searchgrids.KanjiAlphabet, letter rune, position int) searchgrids.EntryList {
-	var char int
-	if searchgrids.IsRegularKanji(letter) {
-		char = int(letter) - 19968
-	} else {
-		char = int(letter) + 7600
-	}
-	return grid.Alphabet[char].Positions[position].List
+func kanjiEntryList(grid searchgrids.KanjiAlphabet, letter rune, position int) searchgrids.PostingList {
+	return grid.Postings(int(letter), position) // The kanji grid is keyed by code point
 }
 
 // This search function narrows the list of search by a lot by merging lists of two consecutive letters and making sure that only the words that are in both lists pass
-func mergeEntryListsFirstWord(old searchgrids.EntryList, new searchgrids.EntryList) searchgrids.EntryList {
-	var result searchgrids.EntryList
-	for _, entry := range old {
-		if match, index := matchInLists(entry.WordID, new); match { // We focus on the elements of the existing list because in most cases (when we have more than a couple of letters) it will be much shorter than the new one
-			var appendix searchgrids.Entry
-			appendix.WordID = entry.WordID
-			appendix.Score = entry.Score
-			for _, hash := range entry.Hash {
-				if matchHashFirstWord(new[index].Hash, hash) {
-					appendix.Hash = append(appendix.Hash, hash)
-				}
-			}
-			if len(appendix.Hash) != 0 {
-				result = append(result, appendix)
-			}
-		}
-	}
-	return result
+// The new list is walked in its compressed form, only the entries that are also in the old one getting their hashes decoded
+func mergeEntryListsFirstWord(old searchgrids.EntryList, new searchgrids.PostingList) searchgrids.EntryList {
+	return intersectEntryLists(old, new, matchHashFirstWord)
+}
+
+func mergeEntryLists(old searchgrids.EntryList, new searchgrids.PostingList) searchgrids.EntryList {
+	return intersectEntryLists(old, new, matchHash)
 }
 
-func mergeEntryLists(old searchgrids.EntryList, new searchgrids.EntryList) searchgrids.EntryList {
+func intersectEntryLists(old searchgrids.EntryList, new searchgrids.PostingList, matchHash func(searchgrids.Hash, uint16) bool) searchgrids.EntryList {
 	var result searchgrids.EntryList
-	for _, entry := range old {
-		if match, index := matchInLists(entry.WordID, new); match { // We focus on the elements of the existing list because in most cases (when we have more than a couple of letters) it will be much shorter than the new one
-			var appendix searchgrids.Entry
-			appendix.WordID = entry.WordID
-			appendix.Score = entry.Score
-			for _, hash := range entry.Hash {
-				if matchHash(new[index].Hash, hash) {
-					appendix.Hash = append(appendix.Hash, hash)
-				}
-			}
-			if len(appendix.Hash) != 0 {
-				result = append(result, appendix)
+	iterator := new.Iterator()
+	for _, entry := range old { // We focus on the elements of the existing list because in most cases (when we have more than a couple of letters) it will be much shorter than the new one
+		if !iterator.Seek(entry.WordID) {
+			break
+		}
+		if iterator.WordID() != entry.WordID {
+			continue
+		}
+		found := iterator.Entry()
+		var appendix searchgrids.Entry
+		appendix.WordID = entry.WordID
+		appendix.Score = entry.Score
+		for _, hash := range entry.Hash {
+			if matchHash(found.Hash, hash) {
+				appendix.Hash = append(appendix.Hash, hash)
 			}
 		}
-	}
-	return result
-}
-
-func matchInLists(wordID int, list searchgrids.EntryList) (match bool, index int) {
-	if list == nil {
-		return false, -1
-	}
-	first := 0
-	last := len(list) - 1
-	var midpoint int
-	for first <= last {
-		midpoint = int((first + last) / 2)
-		if list[midpoint].WordID == wordID {
-			return true, midpoint
-		} else if list[midpoint].WordID < wordID {
-			first = midpoint + 1
-		} else {
-			last = midpoint - 1
+		if len(appendix.Hash) != 0 {
+			result = append(result, appendix)
 		}
 	}
-	return false, midpoint
+	return result
 }
 
 func matchHashFirstWord(Hash searchgrids.Hash, hash uint16) bool {
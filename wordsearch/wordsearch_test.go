@@ -0,0 +1,251 @@
+package wordsearch
+
+import (
+	"japp/env"
+	"japp/searchgrids"
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// entry builds a JMdict entry with a single sense, kanji being empty for words written in kana only
+func entry(kanji, reading, pos string, glosses ...string) jmdict.JmdictEntry {
+	built := jmdict.JmdictEntry{Readings: []jmdict.JmdictReading{{Reading: reading}}}
+	if kanji != "" {
+		built.Kanji = []jmdict.JmdictKanji{{Expression: kanji}}
+	}
+	sense := jmdict.JmdictSense{PartsOfSpeech: []string{pos}}
+	for _, gloss := range glosses {
+		sense.Glossary = append(sense.Glossary, jmdict.JmdictGlossary{Content: gloss})
+	}
+	built.Sense = []jmdict.JmdictSense{sense}
+	return built
+}
+
+// newTable builds an environment with its grids out of the given words and names
+func newTable(words, names []jmdict.JmdictEntry) env.Environment {
+	var table env.Environment
+	table.Dict = &jmdict.Jmdict{Entries: words}
+	table.Forms = env.NewForms(table.Dict)
+	table.English, table.Kana, table.Kanji = searchgrids.GenerateAlphabets(*table.Dict)
+	table.EnglishSuffix, table.KanaSuffix, table.KanjiSuffix = searchgrids.GenerateSuffixAlphabets(*table.Dict)
+	if names != nil {
+		table.Names = &jmdict.Jmdict{Entries: names}
+		table.NameForms = env.NewForms(table.Names)
+		table.NameEnglish, table.NameKana, table.NameKanji = searchgrids.GenerateAlphabets(*table.Names)
+	}
+	return table
+}
+
+const (
+	noun      = "noun (common) (futsuumeishi)"
+	ichidan   = "Ichidan verb"
+	godanU    = "Godan verb with 'u' ending"
+	godanRu   = "Godan verb with 'ru' ending"
+	adjective = "adjective (keiyoushi)"
+)
+
+func testTable() env.Environment {
+	return newTable([]jmdict.JmdictEntry{
+		entry("猫", "ねこ", noun, "cat"),
+		entry("食べる", "たべる", ichidan, "to eat"),
+		entry("食う", "くう", godanU, "to eat"),
+		entry("食べ物", "たべもの", noun, "food"),
+		entry("𠮟る", "しかる", godanRu, "to scold"),
+		entry("高い", "たかい", adjective, "high", "tall"),
+		entry("田中", "でんちゅう", noun, "in the rice field"),
+		entry("", "スゴイ", adjective, "amazing"),
+		entry("", "コーヒー", noun, "coffee"),
+		entry("景気", "けいき", noun, "business conditions"),
+		entry("", "ケーキ", noun, "cake"),
+		entry("一ヶ月", "いっかげつ", noun, "one month"),
+		entry("水", "みず", noun, "water (esp. cool or cold)"),
+		entry("水道", "すいどう", noun, "water supply"),
+		entry("大学", "だいがく", noun, "university"),
+		entry("学生", "がくせい", noun, "student"),
+	}, []jmdict.JmdictEntry{
+		entry("田中", "たなか", "surname", "Tanaka"),
+		entry("田中屋", "たなかや", "company name", "Tanakaya"),
+	})
+}
+
+// found lists the first reading of every result, which is enough to tell the entries of the test table apart
+func found(table env.Environment, results ResultEntries) []string {
+	var readings []string
+	for _, result := range results {
+		dict := table.Dict
+		if result.Name {
+			dict = table.Names
+		}
+		readings = append(readings, dict.Entries[result.Entry.WordID].Readings[0].Reading)
+	}
+	return readings
+}
+
+// sameSet compares two lists of readings regardless of their order
+func sameSet(first, second []string) bool {
+	counts := map[string]int{}
+	for _, text := range first {
+		counts[text]++
+	}
+	for _, text := range second {
+		counts[text]--
+	}
+	for _, count := range counts {
+		if count != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sameStrings(first, second []string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExactNamesInAutoResults(t *testing.T) {
+	table := testTable()
+	results := Search(table, "田中", Options{})
+	if readings := found(table, results); !sameStrings(readings, []string{"でんちゅう", "たなか"}) {
+		t.Fatalf("田中 found %v, expected the word and then the exact name", readings)
+	}
+	if results[0].Name || !results[1].Name {
+		t.Errorf("田中 lists the name before the word")
+	}
+	if readings := found(table, Search(table, "田中", Options{Dictionary: DictionaryWords})); !sameStrings(readings, []string{"でんちゅう"}) {
+		t.Errorf("田中 in words only found %v", readings)
+	}
+	if readings := found(table, Search(table, "田中", Options{Dictionary: DictionaryNames})); !sameStrings(readings, []string{"たなか", "たなかや"}) {
+		t.Errorf("田中 in names only found %v", readings)
+	}
+}
+
+func TestExampleCount(t *testing.T) {
+	table := testTable()
+	for _, test := range []struct{ examples, count int }{{0, DefaultExamples}, {NoExamples, 0}, {5, 5}} {
+		page := SearchPage(table, "猫", Options{Examples: test.examples})
+		if page.ExampleCount() != test.count {
+			t.Errorf("Examples %v gives %v sentences per result, expected %v", test.examples, page.ExampleCount(), test.count)
+		}
+	}
+}
+
+// 𠮟 (U+20B9F) is outside the Basic Multilingual Plane, and has to be found like any other kanji
+func TestNonBMPKanji(t *testing.T) {
+	table := testTable()
+	if list := kanjiResults(table.Kanji, parseKanji("𠮟る")); len(list) != 1 || table.Dict.Entries[list[0].WordID].Readings[0].Reading != "しかる" {
+		t.Errorf("kanjiResults of 𠮟る gives %v, expected しかる", list)
+	}
+	for _, query := range []string{"𠮟る", "𠮟"} {
+		if readings := found(table, Search(table, query, Options{})); !sameStrings(readings, []string{"しかる"}) {
+			t.Errorf("%v found %v, expected しかる", query, readings)
+		}
+	}
+}
+
+// The okurigana of every word of a kanji query are checked, not only the ones of the first word
+func TestKanjiQueryOkurigana(t *testing.T) {
+	table := testTable()
+	if readings := found(table, Search(table, "食べる", Options{})); !sameStrings(readings, []string{"たべる"}) {
+		t.Errorf("食べる found %v, expected たべる", readings)
+	}
+	if readings := found(table, Search(table, "食べる 食う", Options{})); len(readings) != 0 {
+		t.Errorf("食べる 食う found %v, expected nothing since no kanji form starts with 食う", readings)
+	}
+	month := entry("一ヶ月", "いっかげつ", noun, "one month")
+	month.Kanji = append(month.Kanji, jmdict.JmdictKanji{Expression: "一か月"})
+	table = newTable([]jmdict.JmdictEntry{month, entry("一つ", "ひとつ", noun, "one")}, nil)
+	if readings := found(table, Search(table, "一ヶ月 一か月", Options{})); !sameStrings(readings, []string{"いっかげつ"}) {
+		t.Errorf("一ヶ月 一か月 found %v, expected the entry having both forms", readings)
+	}
+	if readings := found(table, Search(table, "一ヶ月 一つ", Options{})); len(readings) != 0 {
+		t.Errorf("一ヶ月 一つ found %v, expected nothing", readings)
+	}
+}
+
+// Kanji forms and readings are compared with queries in their normalized form, whichever way the dictionary writes them
+func TestNormalizedForms(t *testing.T) {
+	table := newTable([]jmdict.JmdictEntry{entry("時々", "ときどき", "adverb (fukushi)", "sometimes"), entry("", "いすゞ", noun, "Isuzu")}, nil)
+	for _, query := range []string{"時々", "時時", "いすゞ", "いすず"} {
+		results := Search(table, query, Options{})
+		if len(results) != 1 || results[0].Tier != TierExactHeadword {
+			t.Errorf("%v found %v, expected a single exact headword", query, found(table, results))
+		}
+	}
+	for _, query := range []string{"時?", "いす?"} {
+		if results := Search(table, query, Options{}); len(results) != 1 {
+			t.Errorf("the pattern %v found %v, expected a single word", query, found(table, results))
+		}
+	}
+}
+
+// Folding the scripts also matches long vowels written with ー on one side and with kana on the other, after the matches spelled as typed
+func TestFoldKanaLongVowels(t *testing.T) {
+	table := newTable([]jmdict.JmdictEntry{
+		entry("", "コーヒー", noun, "coffee"),
+		entry("暴徒", "ぼうと", noun, "rioter"),
+		entry("", "ボート", noun, "boat"),
+		entry("景気", "けいき", noun, "business conditions"),
+		entry("", "ケーキ", noun, "cake"),
+		entry("", "メール", noun, "mail"),
+		entry("珈琲", "こうひい", noun, "coffee"),
+	}, nil)
+	folded := Options{FoldKana: true}
+	for _, test := range []struct {
+		query    string
+		readings []string
+	}{
+		{"こうひい", []string{"こうひい", "コーヒー"}},
+		{"コーヒー", []string{"コーヒー", "こうひい"}},
+		{"めえる", []string{"メール"}},
+		{"めいる", nil}, // ー after an e is ええ, as in お姉さん, not the えい of 景気
+		{"ケーキ", []string{"ケーキ"}},
+	} {
+		if readings := found(table, Search(table, test.query, folded)); !sameStrings(readings, test.readings) {
+			t.Errorf("%v found %v, expected %v", test.query, readings, test.readings)
+		}
+	}
+	results := Search(table, "ボート", folded)
+	if readings := found(table, results); len(readings) != 2 || readings[0] != "ボート" || readings[1] != "ぼうと" {
+		t.Fatalf("ボート found %v, expected ボート and then 暴徒", readings)
+	}
+	if results[0].Tier != TierExactHeadword || results[1].Tier >= TierPrefix {
+		t.Errorf("ボート gives the tiers %v and %v, expected 暴徒 not to be an exact match", results[0].Tier, results[1].Tier)
+	}
+	if readings := found(table, Search(table, "こうひい", Options{})); !sameStrings(readings, []string{"こうひい"}) {
+		t.Errorf("こうひい found %v without folding, expected only こうひい", readings)
+	}
+}
+
+// A dictionary form guessed from a conjugation never goes ahead of a word spelled exactly as the query
+func TestLiteralAboveDeinflected(t *testing.T) {
+	table := newTable([]jmdict.JmdictEntry{
+		entry("居る", "いる", ichidan, "to be"),
+		entry("色", "いろ", noun, "colour"),
+		entry("高い", "たかい", adjective, "high"),
+		entry("高さ", "たかさ", noun, "height"),
+		entry("", "する", "suru verb - included", "to do"),
+		entry("下", "した", noun, "below"),
+	}, nil)
+	for _, test := range []struct{ query, literal, deinflected string }{
+		{"いろ", "いろ", "いる"},
+		{"高さ", "たかさ", "たかい"},
+		{"した", "した", "する"},
+	} {
+		readings := found(table, Search(table, test.query, Options{}))
+		if len(readings) != 2 || readings[0] != test.literal || readings[1] != test.deinflected {
+			t.Errorf("%v found %v, expected %v and then %v", test.query, readings, test.literal, test.deinflected)
+		}
+	}
+	if readings := found(table, Search(table, "高くない", Options{})); !sameStrings(readings, []string{"たかい"}) {
+		t.Errorf("高くない found %v, expected たかい", readings)
+	}
+}